@@ -1,22 +1,28 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/appwrite/sdk-for-go/appwrite"
+	"github.com/appwrite/sdk-for-go/client"
 	"github.com/appwrite/sdk-for-go/id"
 	"github.com/appwrite/sdk-for-go/permission"
 	"github.com/appwrite/sdk-for-go/role"
+	"github.com/appwrite/sdk-for-go/storage"
 	"github.com/appwrite/sdk-for-go/tablesdb"
 )
 
 // Status constants for recipe request tracking
 const (
-	StatusRequested  = "REQUESTED"
-	StatusInProgress = "IN_PROGRESS"
-	StatusCompleted  = "COMPLETED"
-	StatusFailed     = "FAILED"
+	StatusRequested   = "REQUESTED"
+	StatusInProgress  = "IN_PROGRESS"
+	StatusCompleted   = "COMPLETED"
+	StatusNeedsReview = "NEEDS_REVIEW"
+	StatusFailed      = "FAILED"
 )
 
 // Default Appwrite configuration
@@ -31,12 +37,16 @@ const (
 // RecipeRequestStore defines the interface for recipe request operations
 type RecipeRequestStore interface {
 	UpdateStatus(documentID, status string) error
+	CompleteRequest(documentID string, report *CompletenessReport, canonicalURL string) error
+	FailRequest(documentID string, failure *ProcessingError) error
+	SaveDiagnostics(documentID string, diagnostics *Diagnostics) error
 	CreateRecipe(requestID, userID string, recipe *Recipe) (string, error)
 }
 
 // RecipeRequestClient handles database operations for recipe requests
 type RecipeRequestClient struct {
 	tablesdb *tablesdb.TablesDB
+	storage  *storage.Storage
 }
 
 // NewRecipeRequestClient creates a new RecipeRequestClient with Appwrite configuration
@@ -61,9 +71,16 @@ func NewRecipeRequestClient() *RecipeRequestClient {
 
 	return &RecipeRequestClient{
 		tablesdb: appwrite.NewTablesDB(client),
+		storage:  appwrite.NewStorage(client),
 	}
 }
 
+// isAppwriteNotFound reports whether err is Appwrite answering 404 Not Found
+func isAppwriteNotFound(err error) bool {
+	var appwriteErr *client.AppwriteError
+	return errors.As(err, &appwriteErr) && appwriteErr.GetStatusCode() == http.StatusNotFound
+}
+
 // UpdateStatus updates the status of an existing recipe request
 func (c *RecipeRequestClient) UpdateStatus(documentID, status string) error {
 	data := map[string]interface{}{
@@ -82,6 +99,90 @@ func (c *RecipeRequestClient) UpdateStatus(documentID, status string) error {
 	return nil
 }
 
+// CompleteRequest marks a request COMPLETED or NEEDS_REVIEW according to the
+// completeness report, storing the score and missing fields for the app and
+// the canonical URL of the page the recipe came from
+func (c *RecipeRequestClient) CompleteRequest(documentID string, report *CompletenessReport, canonicalURL string) error {
+	_, err := c.tablesdb.UpdateRow(
+		DatabaseID,
+		CollectionID,
+		documentID,
+		c.tablesdb.WithUpdateRowData(completionData(report, canonicalURL)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update recipe request status to %s: %w", report.Status(), err)
+	}
+	return nil
+}
+
+// completionData builds the recipe request update for a completeness report
+func completionData(report *CompletenessReport, canonicalURL string) map[string]interface{} {
+	data := map[string]interface{}{
+		"status":             report.Status(),
+		"completeness_score": report.Score,
+		"missing_fields":     report.MissingFields,
+	}
+	if canonicalURL != "" {
+		data["canonical_url"] = canonicalURL
+	}
+	return data
+}
+
+// FailRequest marks a request FAILED, storing the classified error so the app
+// can explain the failure and decide whether to offer a retry
+func (c *RecipeRequestClient) FailRequest(documentID string, failure *ProcessingError) error {
+	_, err := c.tablesdb.UpdateRow(
+		DatabaseID,
+		CollectionID,
+		documentID,
+		c.tablesdb.WithUpdateRowData(failureData(failure)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update recipe request status to %s: %w", StatusFailed, err)
+	}
+	return nil
+}
+
+// failureData builds the recipe request update for a failure. The page
+// classification is only present when the page itself had no recipe.
+func failureData(failure *ProcessingError) map[string]interface{} {
+	data := map[string]interface{}{
+		"status":        StatusFailed,
+		"error_code":    string(failure.Code),
+		"error_message": failure.Message,
+		"retryable":     failure.Retryable,
+	}
+	if failure.Strategy != "" {
+		data["failed_strategy"] = failure.Strategy
+	}
+	if failure.HTTPStatus != 0 {
+		data["http_status"] = failure.HTTPStatus
+	}
+	if failure.PageClass != "" {
+		data["failure_reason"] = string(failure.PageClass)
+		data["failure_hint"] = failure.PageClass.Hint()
+	}
+	return data
+}
+
+// SaveDiagnostics stores how the request was processed, e.g. every fetch attempt
+func (c *RecipeRequestClient) SaveDiagnostics(documentID string, diagnostics *Diagnostics) error {
+	data, err := diagnostics.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize diagnostics: %w", err)
+	}
+	_, err = c.tablesdb.UpdateRow(
+		DatabaseID,
+		CollectionID,
+		documentID,
+		c.tablesdb.WithUpdateRowData(map[string]interface{}{"diagnostics": data}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save recipe request diagnostics: %w", err)
+	}
+	return nil
+}
+
 // CreateRecipe creates a new recipe document linked to a recipe request
 func (c *RecipeRequestClient) CreateRecipe(requestID, userID string, recipe *Recipe) (string, error) {
 	data := recipeToMap(requestID, userID, recipe)
@@ -140,12 +241,18 @@ func recipeToMap(requestID, userID string, recipe *Recipe) map[string]interface{
 	if recipe.Keywords != nil {
 		data["keywords"] = *recipe.Keywords
 	}
+	if len(recipe.SuitableForDiet) > 0 {
+		data["suitable_for_diet"] = recipe.SuitableForDiet
+	}
 	if recipe.DatePublished != nil {
 		data["date_published"] = *recipe.DatePublished
 	}
 	if recipe.DateModified != nil {
 		data["date_modified"] = *recipe.DateModified
 	}
+	if recipe.CanonicalURL != "" {
+		data["canonical_url"] = recipe.CanonicalURL
+	}
 
 	// Image array
 	if len(recipe.Image) > 0 {
@@ -203,5 +310,14 @@ func recipeToMap(requestID, userID string, recipe *Recipe) map[string]interface{
 		}
 	}
 
+	// Provenance (method and strategy flattened for querying, full detail as JSON)
+	if recipe.Provenance != nil {
+		data["extraction_method"] = string(recipe.Provenance.Method)
+		data["extraction_strategy"] = recipe.Provenance.Strategy
+		if provenanceJSON, err := json.Marshal(recipe.Provenance); err == nil {
+			data["provenance"] = string(provenanceJSON)
+		}
+	}
+
 	return data
 }
@@ -44,6 +44,11 @@ func TestStatusConstants(t *testing.T) {
 			constant: StatusCompleted,
 			expected: "COMPLETED",
 		},
+		{
+			name:     "StatusNeedsReview",
+			constant: StatusNeedsReview,
+			expected: "NEEDS_REVIEW",
+		},
 		{
 			name:     "StatusFailed",
 			constant: StatusFailed,
@@ -226,3 +231,130 @@ func TestRecipeRequestClient_UpdateStatus_Integration(t *testing.T) {
 
 	t.Log("Integration test completed successfully")
 }
+
+func TestRecipeToMap_Provenance(t *testing.T) {
+	recipe := &Recipe{
+		Name:  "Soup",
+		Image: []string{"https://example.com/soup.jpg"},
+		Provenance: &Provenance{
+			Strategy: "HTTP Client",
+			Method:   MethodJSONLD,
+			Fields: map[string]FieldProvenance{
+				"name": {Source: SourceJSONLD, Confidence: 0.95},
+			},
+		},
+	}
+
+	data := recipeToMap("request-1", "user-1", recipe)
+
+	if data["extraction_method"] != "json-ld" {
+		t.Errorf("extraction_method = %v, want json-ld", data["extraction_method"])
+	}
+	if data["extraction_strategy"] != "HTTP Client" {
+		t.Errorf("extraction_strategy = %v, want HTTP Client", data["extraction_strategy"])
+	}
+	want := `{"strategy":"HTTP Client","method":"json-ld","fields":{"name":{"source":"json-ld","confidence":0.95}}}`
+	if data["provenance"] != want {
+		t.Errorf("provenance = %v, want %s", data["provenance"], want)
+	}
+}
+
+func TestRecipeToMap_WithoutProvenance(t *testing.T) {
+	data := recipeToMap("request-1", "user-1", &Recipe{Name: "Soup"})
+
+	for _, key := range []string{"provenance", "extraction_method", "extraction_strategy"} {
+		if _, ok := data[key]; ok {
+			t.Errorf("expected no %s key", key)
+		}
+	}
+}
+
+func TestCompletionData(t *testing.T) {
+	report := &CompletenessReport{Score: 70, MissingFields: []string{MissingYield, MissingTimes}}
+
+	data := completionData(report, "https://example.com/recipe")
+
+	if data["status"] != StatusNeedsReview {
+		t.Errorf("status = %v, want %s", data["status"], StatusNeedsReview)
+	}
+	if data["completeness_score"] != 70 {
+		t.Errorf("completeness_score = %v, want 70", data["completeness_score"])
+	}
+	missing, ok := data["missing_fields"].([]string)
+	if !ok || len(missing) != 2 {
+		t.Errorf("missing_fields = %v, want [yield times]", data["missing_fields"])
+	}
+	if data["canonical_url"] != "https://example.com/recipe" {
+		t.Errorf("canonical_url = %v, want https://example.com/recipe", data["canonical_url"])
+	}
+
+	if _, ok := completionData(report, "")["canonical_url"]; ok {
+		t.Error("expected no canonical_url key without a canonical URL")
+	}
+}
+
+func TestFailureData(t *testing.T) {
+	tests := []struct {
+		name         string
+		failure      *ProcessingError
+		wantReason   interface{}
+		wantStrategy interface{}
+		wantStatus   interface{}
+	}{
+		{
+			name: "classified page",
+			failure: &ProcessingError{
+				Code:       CodeNoRecipe,
+				Message:    "no recipe",
+				Strategy:   "HTTPClient",
+				HTTPStatus: 200,
+				PageClass:  PageClassPaywall,
+			},
+			wantReason:   "paywall",
+			wantStrategy: "HTTPClient",
+			wantStatus:   200,
+		},
+		{
+			name: "unclassified failure",
+			failure: &ProcessingError{
+				Code:      CodeSaveFailed,
+				Message:   "connection reset",
+				Retryable: true,
+			},
+			wantReason:   nil,
+			wantStrategy: nil,
+			wantStatus:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := failureData(tt.failure)
+
+			if data["status"] != StatusFailed {
+				t.Errorf("status = %v, want %s", data["status"], StatusFailed)
+			}
+			if data["error_code"] != string(tt.failure.Code) {
+				t.Errorf("error_code = %v, want %s", data["error_code"], tt.failure.Code)
+			}
+			if data["error_message"] != tt.failure.Message {
+				t.Errorf("error_message = %v, want %q", data["error_message"], tt.failure.Message)
+			}
+			if data["retryable"] != tt.failure.Retryable {
+				t.Errorf("retryable = %v, want %v", data["retryable"], tt.failure.Retryable)
+			}
+			if data["failed_strategy"] != tt.wantStrategy {
+				t.Errorf("failed_strategy = %v, want %v", data["failed_strategy"], tt.wantStrategy)
+			}
+			if data["http_status"] != tt.wantStatus {
+				t.Errorf("http_status = %v, want %v", data["http_status"], tt.wantStatus)
+			}
+			if data["failure_reason"] != tt.wantReason {
+				t.Errorf("failure_reason = %v, want %v", data["failure_reason"], tt.wantReason)
+			}
+			if tt.failure.PageClass != "" && data["failure_hint"] != tt.failure.PageClass.Hint() {
+				t.Errorf("failure_hint = %v, want %q", data["failure_hint"], tt.failure.PageClass.Hint())
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+// This file is shared by recipe-request and recipe-request-processor, which
+// are deployed separately; keep both copies identical.
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters added by share buttons, newsletters
+// and ad platforms that never change the page content
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "gclsrc": true, "dclid": true, "msclkid": true,
+	"yclid": true, "twclid": true, "ttclid": true, "igshid": true, "igsh": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "_gl": true, "_hsenc": true,
+	"_hsmi": true, "mkt_tok": true, "epik": true, "ref_src": true,
+}
+
+// ampParams are query parameters selecting an AMP variant of a page
+var ampParams = map[string]bool{"amp": true, "amp_js_v": true, "usqp": true, "outputtype": true}
+
+// mobileHostPrefixes are subdomains serving mobile or AMP copies of a site
+var mobileHostPrefixes = []string{"m.", "mobile.", "amp."}
+
+// canonicalizeURL returns the canonical form of a recipe URL so the same page
+// submitted in different ways is recognized as one. Scheme and host are
+// lowercased, default ports, credentials and fragments dropped, mobile and AMP
+// variants mapped to the regular page, tracking parameters removed, remaining
+// parameters sorted and trailing slashes trimmed.
+func canonicalizeURL(u *url.URL) *url.URL {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.User = nil
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	host := strings.ToLower(c.Hostname())
+	for _, prefix := range mobileHostPrefixes {
+		if rest, ok := strings.CutPrefix(host, prefix); ok && strings.Contains(rest, ".") {
+			host = rest
+			break
+		}
+	}
+	port := c.Port()
+	if (c.Scheme == "http" && port == "80") || (c.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	c.Host = host
+	if port != "" {
+		c.Host = host + ":" + port
+	}
+
+	// Work on the escaped path so encoded slashes survive
+	path := canonicalPath(u.EscapedPath())
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		c.Path = unescaped
+		c.RawPath = path
+	}
+
+	query := c.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if isTrackingParam(lower) ||
+			(ampParams[lower] && (lower != "outputtype" || strings.EqualFold(query.Get(key), "amp"))) {
+			query.Del(key)
+		}
+	}
+	c.RawQuery = query.Encode()
+	c.ForceQuery = false
+	return &c
+}
+
+// fetchableURL returns the URL to fetch for a submitted recipe URL: the URL
+// as submitted, without credentials, fragment and tracking parameters. Unlike
+// canonicalizeURL it keeps the host, path and remaining parameters unchanged,
+// since a mobile or AMP host may be the only one serving the page.
+func fetchableURL(u *url.URL) *url.URL {
+	c := *u
+	c.User = nil
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	// Filter the raw query so the remaining parameters keep their order and encoding
+	var kept []string
+	for _, pair := range strings.Split(c.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if pair != "" && !isTrackingParam(strings.ToLower(key)) {
+			kept = append(kept, pair)
+		}
+	}
+	c.RawQuery = strings.Join(kept, "&")
+	c.ForceQuery = false
+	return &c
+}
+
+// isTrackingParam reports whether the lowercased query parameter key only
+// tracks where a link was shared
+func isTrackingParam(key string) bool {
+	return trackingParams[key] || strings.HasPrefix(key, "utm_")
+}
+
+// canonicalPath removes AMP path variants (/amp/..., .../amp, ....amp.html)
+// and the trailing slash from an escaped path
+func canonicalPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && strings.EqualFold(segments[0], "amp") {
+		segments = segments[1:]
+	}
+	if len(segments) > 1 && strings.EqualFold(segments[len(segments)-1], "amp") {
+		segments = segments[:len(segments)-1]
+	}
+	last := segments[len(segments)-1]
+	if strings.HasSuffix(strings.ToLower(last), ".amp.html") {
+		segments[len(segments)-1] = last[:len(last)-len(".amp.html")] + ".html"
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// canonicalURLString parses and canonicalizes a URL string
+func canonicalURLString(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return canonicalizeURL(u).String(), nil
+}
+
+// fetchableURLString parses a URL string and returns the URL to fetch for it
+func fetchableURLString(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return fetchableURL(u).String(), nil
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// decodeHTML transcodes an HTML page to UTF-8 so goquery sees the text the
+// site intended. The encoding comes from the byte order mark, the charset in
+// the Content-Type header or a <meta charset> / http-equiv tag in the first
+// 1024 bytes, in that order. It returns the decoded page and encoding name.
+func decodeHTML(body []byte, contentType string) (string, string) {
+	encoding, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// Without a BOM or header, a page that is valid UTF-8 throughout is UTF-8:
+	// sites often mislabel UTF-8 pages in <meta>, and undeclared pages would
+	// otherwise default to windows-1252 after looking at only the first 1024 bytes
+	if !certain && utf8.Valid(body) {
+		name = "utf-8"
+	}
+
+	decoded := string(body)
+	if name != "utf-8" {
+		transcoded, err := encoding.NewDecoder().Bytes(body)
+		if err != nil {
+			name = "utf-8"
+		} else {
+			decoded = string(transcoded)
+		}
+	}
+	return strings.TrimPrefix(decoded, "\uFEFF"), name
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeHTML(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		wantName    string
+		wantCharset string
+	}{
+		{"windows-1252.html", "text/html", "Crème brûlée", "windows-1252"},
+		{"iso-8859-7.html", "text/html", "Μουσακάς με μελιτζάνες", "iso-8859-7"},
+		{"shift_jis.html", "text/html", "親子丼", "shift_jis"},
+		{"greek-header-only.html", "text/html; charset=ISO-8859-7", "Σπανακόπιτα", "iso-8859-7"},
+		{"utf-8-bom.html", "text/html", "Crème brûlée", "utf-8"},
+		{"utf-16le-bom.html", "text/html", "Σπανακόπιτα", "utf-16le"},
+		{"utf-8-mislabeled.html", "text/html", "Μουσακάς", "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "charset", tt.file))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			page, charset := decodeHTML(body, tt.contentType)
+
+			if charset != tt.wantCharset {
+				t.Errorf("charset = %q, want %q", charset, tt.wantCharset)
+			}
+			recipe, err := extractRecipeFromHTML(page, nil)
+			if err != nil || recipe == nil {
+				t.Fatalf("extractRecipeFromHTML() = %v, %v", recipe, err)
+			}
+			if recipe.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDecodeHTML_UndeclaredUTF8(t *testing.T) {
+	// Non-ASCII text only after the first 1024 bytes must not be read as windows-1252
+	body := make([]byte, 0, 2048)
+	body = append(body, "<html><head><title>"...)
+	for len(body) < 1500 {
+		body = append(body, ' ')
+	}
+	body = append(body, "Γιουβέτσι</title></head></html>"...)
+
+	page, charset := decodeHTML(body, "text/html")
+
+	if charset != "utf-8" || page != string(body) {
+		t.Errorf("decodeHTML() charset = %q, page changed = %v", charset, page != string(body))
+	}
+}
+
+func TestHTTPClientStrategy_TranscodesPage(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "charset", "greek-header-only.html"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-7")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	recipe, err := newLocalHTTPClientStrategy().Fetch(context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if recipe.Name != "Σπανακόπιτα" {
+		t.Errorf("Name = %q, want Σπανακόπιτα", recipe.Name)
+	}
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appwrite/sdk-for-go/tablesdb"
+)
+
+// CircuitBreakerTableID is the table holding the state of each circuit breaker
+const CircuitBreakerTableID = "circuit_breaker"
+
+// Environment variables configuring the Firecrawl circuit breaker
+const (
+	envBreakerThreshold   = "FIRECRAWL_BREAKER_THRESHOLD"
+	envBreakerOpenSeconds = "FIRECRAWL_BREAKER_OPEN_SECONDS"
+)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreakerConfig controls when a circuit opens and for how long
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit
+	FailureThreshold int
+	// OpenDuration is how long calls are short-circuited before a probe is let through
+	OpenDuration time.Duration
+}
+
+// DefaultCircuitBreakerConfig is used unless overridden from the environment
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	OpenDuration:     2 * time.Minute,
+}
+
+// CircuitBreakerConfigFromEnv returns DefaultCircuitBreakerConfig with
+// overrides from the environment. Missing or invalid values keep the default;
+// a threshold of 0 disables the breaker.
+func CircuitBreakerConfigFromEnv() CircuitBreakerConfig {
+	config := DefaultCircuitBreakerConfig
+	if n, ok := envInt(envBreakerThreshold); ok && n >= 0 {
+		config.FailureThreshold = n
+	}
+	if seconds, ok := envInt(envBreakerOpenSeconds); ok && seconds >= 1 {
+		config.OpenDuration = time.Duration(seconds) * time.Second
+	}
+	return config
+}
+
+// CircuitOpenError reports that a strategy was short-circuited because its
+// service failed repeatedly. It wraps ErrStrategyUnavailable so the next
+// strategy is tried.
+type CircuitOpenError struct {
+	Name    string
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s fallback temporarily unavailable after repeated failures, retrying after %s",
+		e.Name, e.RetryAt.UTC().Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrStrategyUnavailable
+}
+
+// circuitState is the stored state of one circuit breaker
+type circuitState struct {
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	// OpenedAt is when the circuit last opened, or when the half-open probe
+	// started (Unix milliseconds)
+	OpenedAt int64 `json:"opened_at"`
+}
+
+// CircuitStore holds circuit states so they are shared across executions
+type CircuitStore interface {
+	// Load returns the state of the named circuit, closed if there is none
+	Load(ctx context.Context, name string) (circuitState, error)
+	// Save replaces the state of the named circuit
+	Save(ctx context.Context, name string, state circuitState) error
+}
+
+// memoryCircuitStore keeps circuit states in memory, shared only within one
+// function instance
+type memoryCircuitStore struct {
+	mu     sync.Mutex
+	states map[string]circuitState
+}
+
+// newMemoryCircuitStore creates an empty memoryCircuitStore
+func newMemoryCircuitStore() *memoryCircuitStore {
+	return &memoryCircuitStore{states: map[string]circuitState{}}
+}
+
+// Load returns the in-memory state of the named circuit
+func (s *memoryCircuitStore) Load(_ context.Context, name string) (circuitState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		return circuitState{State: CircuitClosed}, nil
+	}
+	return state, nil
+}
+
+// Save stores the state of the named circuit in memory
+func (s *memoryCircuitStore) Save(_ context.Context, name string, state circuitState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = state
+	return nil
+}
+
+// appwriteCircuitStore keeps circuit states in the circuit_breaker table,
+// one row per circuit named by its row ID
+type appwriteCircuitStore struct {
+	tablesdb *tablesdb.TablesDB
+}
+
+// CircuitStore returns a CircuitStore backed by the circuit_breaker table
+func (c *RecipeRequestClient) CircuitStore() CircuitStore {
+	return &appwriteCircuitStore{tablesdb: c.tablesdb}
+}
+
+// Load reads the row of the named circuit
+func (s *appwriteCircuitStore) Load(_ context.Context, name string) (circuitState, error) {
+	row, err := s.tablesdb.GetRow(DatabaseID, CircuitBreakerTableID, name)
+	if isAppwriteNotFound(err) {
+		return circuitState{State: CircuitClosed}, nil
+	}
+	if err != nil {
+		return circuitState{}, fmt.Errorf("failed to read circuit %s: %w", name, err)
+	}
+	var state circuitState
+	if err := row.Decode(&state); err != nil {
+		return circuitState{}, fmt.Errorf("failed to decode circuit %s: %w", name, err)
+	}
+	return state, nil
+}
+
+// Save upserts the row of the named circuit
+func (s *appwriteCircuitStore) Save(_ context.Context, name string, state circuitState) error {
+	_, err := s.tablesdb.UpsertRow(DatabaseID, CircuitBreakerTableID, name, s.tablesdb.WithUpsertRowData(map[string]interface{}{
+		"state":     state.State,
+		"failures":  state.Failures,
+		"opened_at": state.OpenedAt,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to save circuit %s: %w", name, err)
+	}
+	return nil
+}
+
+// CircuitBreaker short-circuits calls to a service after repeated failures.
+// Closed, calls go through and consecutive failures are counted; at the
+// threshold the circuit opens and calls fail right away. Once OpenDuration has
+// passed, one execution is let through as a half-open probe: its success
+// closes the circuit, its failure opens it again. The state is stored so all
+// executions share it. A nil breaker lets every call through.
+type CircuitBreaker struct {
+	name   string
+	store  CircuitStore
+	config CircuitBreakerConfig
+	logger *Logger
+	now    func() time.Time
+}
+
+// NewCircuitBreaker creates a CircuitBreaker keeping its state in store
+func NewCircuitBreaker(name string, store CircuitStore, config CircuitBreakerConfig, logger *Logger) *CircuitBreaker {
+	return &CircuitBreaker{name: name, store: store, config: config, logger: logger, now: time.Now}
+}
+
+// enabled reports whether the breaker short-circuits anything
+func (b *CircuitBreaker) enabled() bool {
+	return b != nil && b.config.FailureThreshold > 0
+}
+
+// Allow reports whether a call may go through, returning a CircuitOpenError
+// if not. Store failures are logged and let the call through.
+func (b *CircuitBreaker) Allow(ctx context.Context) error {
+	if !b.enabled() {
+		return nil
+	}
+	state, err := b.store.Load(ctx, b.name)
+	if err != nil {
+		b.logWarn("Circuit state unavailable, calling anyway", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	if state.State == CircuitClosed || state.State == "" {
+		return nil
+	}
+
+	now := b.now()
+	retryAt := time.UnixMilli(state.OpenedAt).Add(b.config.OpenDuration)
+	if now.Before(retryAt) {
+		// Open, or half-open with a probe still running
+		return &CircuitOpenError{Name: b.name, RetryAt: retryAt}
+	}
+
+	// Let this call through as the probe. A probe that never reports back
+	// is replaced after another OpenDuration.
+	b.logInfo("Circuit half-open, probing", nil)
+	if err := b.store.Save(ctx, b.name, circuitState{State: CircuitHalfOpen, Failures: state.Failures, OpenedAt: now.UnixMilli()}); err != nil {
+		b.logWarn("Error saving circuit state", map[string]interface{}{"error": err.Error()})
+	}
+	return nil
+}
+
+// Record reports the outcome of a call let through by Allow
+func (b *CircuitBreaker) Record(ctx context.Context, failed bool) {
+	if !b.enabled() {
+		return
+	}
+	state, err := b.store.Load(ctx, b.name)
+	if err != nil {
+		b.logWarn("Circuit state unavailable, outcome not recorded", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	next := state
+	switch {
+	case !failed:
+		next = circuitState{State: CircuitClosed}
+		if state.State == CircuitClosed && state.Failures == 0 {
+			// Nothing changed; spare the write on the common path
+			return
+		}
+		if state.State != CircuitClosed {
+			b.logInfo("Circuit closed", nil)
+		}
+	case state.State == CircuitHalfOpen:
+		next = circuitState{State: CircuitOpen, Failures: state.Failures + 1, OpenedAt: b.now().UnixMilli()}
+		b.logWarn("Probe failed, circuit open again", map[string]interface{}{"open_seconds": b.config.OpenDuration.Seconds()})
+	case state.State == CircuitOpen:
+		// A call that started before the circuit opened; it stays open
+		return
+	default:
+		next.State = CircuitClosed
+		next.Failures++
+		if next.Failures >= b.config.FailureThreshold {
+			next = circuitState{State: CircuitOpen, Failures: next.Failures, OpenedAt: b.now().UnixMilli()}
+			b.logWarn("Circuit open after repeated failures", map[string]interface{}{
+				"failures":     next.Failures,
+				"open_seconds": b.config.OpenDuration.Seconds(),
+			})
+		}
+	}
+
+	if err := b.store.Save(ctx, b.name, next); err != nil {
+		b.logWarn("Error saving circuit state", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// logInfo logs an info message if logger is available
+func (b *CircuitBreaker) logInfo(msg string, fields map[string]interface{}) {
+	if b.logger != nil {
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		fields["circuit"] = b.name
+		b.logger.Info("circuit_breaker", msg, fields)
+	}
+}
+
+// logWarn logs a warning if logger is available
+func (b *CircuitBreaker) logWarn(msg string, fields map[string]interface{}) {
+	if b.logger != nil {
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		fields["circuit"] = b.name
+		b.logger.Warn("circuit_breaker", msg, fields)
+	}
+}
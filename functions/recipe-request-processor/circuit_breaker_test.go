@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	breaker := NewCircuitBreaker("firecrawl", newMemoryCircuitStore(), CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute}, nil)
+	breaker.now = func() time.Time { return now }
+
+	state := func() string {
+		s, _ := breaker.store.Load(ctx, "firecrawl")
+		return s.State
+	}
+
+	// Closed: failures below the threshold and successes reset the count
+	breaker.Record(ctx, true)
+	breaker.Record(ctx, false)
+	breaker.Record(ctx, true)
+	if err := breaker.Allow(ctx); err != nil || state() != CircuitClosed {
+		t.Fatalf("Allow() = %v in state %s, want closed circuit", err, state())
+	}
+
+	// Open after consecutive failures
+	breaker.Record(ctx, true)
+	var openErr *CircuitOpenError
+	if err := breaker.Allow(ctx); !errors.As(err, &openErr) {
+		t.Fatalf("Allow() = %v, want CircuitOpenError", err)
+	}
+	if !errors.Is(openErr, ErrStrategyUnavailable) {
+		t.Error("CircuitOpenError should wrap ErrStrategyUnavailable")
+	}
+	if want := now.Add(time.Minute); !openErr.RetryAt.Equal(want) {
+		t.Errorf("RetryAt = %v, want %v", openErr.RetryAt, want)
+	}
+
+	// Half-open after the open duration: one probe, the rest still rejected
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(ctx); err != nil || state() != CircuitHalfOpen {
+		t.Fatalf("Allow() = %v in state %s, want a half-open probe", err, state())
+	}
+	if err := breaker.Allow(ctx); err == nil {
+		t.Error("Allow() during probe = nil, want CircuitOpenError")
+	}
+
+	// A failed probe opens the circuit again
+	breaker.Record(ctx, true)
+	if err := breaker.Allow(ctx); err == nil || state() != CircuitOpen {
+		t.Fatalf("Allow() = %v in state %s, want open circuit after failed probe", err, state())
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if err := breaker.Allow(ctx); err != nil {
+		t.Fatalf("Allow() = %v, want probe", err)
+	}
+	breaker.Record(ctx, false)
+	if err := breaker.Allow(ctx); err != nil || state() != CircuitClosed {
+		t.Errorf("Allow() = %v in state %s, want closed circuit after successful probe", err, state())
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	var nilBreaker *CircuitBreaker
+	if err := nilBreaker.Allow(context.Background()); err != nil {
+		t.Errorf("nil breaker Allow() = %v, want nil", err)
+	}
+	nilBreaker.Record(context.Background(), true)
+
+	breaker := NewCircuitBreaker("firecrawl", newMemoryCircuitStore(), CircuitBreakerConfig{FailureThreshold: 0, OpenDuration: time.Minute}, nil)
+	for i := 0; i < 3; i++ {
+		breaker.Record(context.Background(), true)
+	}
+	if err := breaker.Allow(context.Background()); err != nil {
+		t.Errorf("disabled breaker Allow() = %v, want nil", err)
+	}
+}
+
+func TestFirecrawlAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		recorded   int
+		wantStatus int
+	}{
+		{"recorded status", "failed to parse error response: invalid character '<'", http.StatusBadGateway, http.StatusBadGateway},
+		{"recorded status wins over the message", "Internal Server Error: Failed to scrape URL. boom", http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"message with a status name", "Payment Required: Failed to scrape URL. Insufficient credits", 0, http.StatusPaymentRequired},
+		{"message with a status code", "Unexpected error during scrape URL: Status code 401. Unauthorized", 0, http.StatusUnauthorized},
+		{"nothing known", "failed to parse error response: invalid character '<'", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr *FirecrawlAPIError
+			if !errors.As(firecrawlAPIError(errors.New(tt.message), tt.recorded), &apiErr) {
+				t.Fatal("firecrawlAPIError() did not return a FirecrawlAPIError")
+			}
+			if apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFirecrawlStrategy_APIErrorStatus(t *testing.T) {
+	// A gateway error page the SDK can't parse still reports its status
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html><body>Bad Gateway</body></html>")
+	}))
+	defer server.Close()
+
+	strategy := NewFirecrawlStrategy(nil, nil)
+	strategy.apiKey = "test-key"
+	strategy.apiURL = server.URL
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+
+	_, err := strategy.Fetch(context.Background(), "https://example.com/recipe")
+	var apiErr *FirecrawlAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Fetch() error = %v, want FirecrawlAPIError with status 502", err)
+	}
+	if !isFirecrawlFailure(err) {
+		t.Error("a gateway error should count as a Firecrawl failure")
+	}
+}
+
+func TestIsFirecrawlFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"out of credits", &FirecrawlAPIError{StatusCode: http.StatusPaymentRequired}, true},
+		{"outage", fmt.Errorf("failed to scrape URL with Firecrawl: %w", &FirecrawlAPIError{StatusCode: 503}), true},
+		{"rejected URL", &FirecrawlAPIError{StatusCode: http.StatusBadRequest}, false},
+		{"unreachable", &NetworkError{Err: errors.New("connection refused")}, true},
+		{"timeout", &TimeoutError{Err: context.DeadlineExceeded}, true},
+		{"no recipe on page", &NoRecipeError{Class: PageClassNoRecipeFound}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFirecrawlFailure(tt.err); got != tt.want {
+				t.Errorf("isFirecrawlFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFirecrawlStrategy_CircuitBreaker runs the strategy against a fake
+// Firecrawl API that is down, then recovers
+func TestFirecrawlStrategy_CircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"success":false,"error":"service unavailable"}`)
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"data":{"rawHtml":"<html><head><script type=\"application/ld+json\">{\"@type\":\"Recipe\",\"name\":\"Soup\",\"image\":\"https://example.com/soup.jpg\",\"recipeIngredient\":[\"water\"]}</script></head></html>"}}`)
+	}))
+	defer server.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	breaker := NewCircuitBreaker("firecrawl", newMemoryCircuitStore(), CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute}, nil)
+	breaker.now = func() time.Time { return now }
+
+	strategy := NewFirecrawlStrategy(nil, nil)
+	strategy.apiKey = "test-key"
+	strategy.apiURL = server.URL
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+	strategy.breaker = breaker
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := strategy.Fetch(ctx, "https://example.com/recipe"); err == nil {
+			t.Fatal("Fetch() error = nil while Firecrawl is down")
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("API calls = %d, want 2", calls.Load())
+	}
+
+	// Open: the strategy fails without calling Firecrawl and falls back
+	_, err := strategy.Fetch(ctx, "https://example.com/recipe")
+	if !errorAs[*CircuitOpenError](err) {
+		t.Fatalf("Fetch() error = %v, want CircuitOpenError", err)
+	}
+	if _, fallback := fallbackFor(err); !fallback {
+		t.Error("open circuit should fall back to the next strategy")
+	}
+	if calls.Load() != 2 {
+		t.Errorf("API calls = %d, want no call while open", calls.Load())
+	}
+
+	// Recovered: the probe succeeds and closes the circuit
+	healthy.Store(true)
+	now = now.Add(time.Minute)
+	recipe, err := strategy.Fetch(ctx, "https://example.com/recipe")
+	if err != nil || recipe.Name != "Soup" {
+		t.Fatalf("Fetch() = %v, %v, want recipe after recovery", recipe, err)
+	}
+	state, _ := breaker.store.Load(ctx, "firecrawl")
+	if state.State != CircuitClosed {
+		t.Errorf("state = %s, want closed", state.State)
+	}
+}
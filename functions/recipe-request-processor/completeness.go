@@ -0,0 +1,54 @@
+package handler
+
+// Completeness fields reported in CompletenessReport.MissingFields.
+// Values are stable identifiers the app uses to prompt for missing data.
+const (
+	MissingIngredients  = "ingredients"
+	MissingInstructions = "instructions"
+	MissingYield        = "yield"
+	MissingTimes        = "times"
+)
+
+// completenessCheck scores one aspect of a recipe
+type completenessCheck struct {
+	field   string
+	weight  int
+	present func(r *Recipe) bool
+}
+
+// completenessChecks lists the aspects a usable recipe needs; weights sum to 100
+var completenessChecks = []completenessCheck{
+	{MissingIngredients, 35, func(r *Recipe) bool { return len(r.RecipeIngredient) > 0 }},
+	{MissingInstructions, 35, func(r *Recipe) bool { return len(flattenInstructions(r.RecipeInstructions)) > 0 }},
+	{MissingYield, 15, func(r *Recipe) bool { return len(r.RecipeYield) > 0 }},
+	{MissingTimes, 15, func(r *Recipe) bool { return r.TotalTime != nil || r.PrepTime != nil || r.CookTime != nil }},
+}
+
+// CompletenessReport summarizes how complete an extracted recipe is
+type CompletenessReport struct {
+	Score         int      `json:"score"`
+	MissingFields []string `json:"missingFields"`
+}
+
+// scoreRecipe checks the recipe for ingredients, instructions, yield and times,
+// returning a 0-100 score and the list of missing fields
+func scoreRecipe(recipe *Recipe) *CompletenessReport {
+	report := &CompletenessReport{MissingFields: []string{}}
+	for _, check := range completenessChecks {
+		if check.present(recipe) {
+			report.Score += check.weight
+		} else {
+			report.MissingFields = append(report.MissingFields, check.field)
+		}
+	}
+	return report
+}
+
+// Status returns the request status for the report: COMPLETED when nothing is
+// missing, NEEDS_REVIEW otherwise so the user is prompted to fill the gaps
+func (r *CompletenessReport) Status() string {
+	if len(r.MissingFields) > 0 {
+		return StatusNeedsReview
+	}
+	return StatusCompleted
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScoreRecipe(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		recipe      *Recipe
+		wantScore   int
+		wantMissing []string
+		wantStatus  string
+	}{
+		{
+			name: "complete recipe",
+			recipe: &Recipe{
+				Name:               "Soup",
+				RecipeIngredient:   []string{"water"},
+				RecipeInstructions: []RecipeInstruction{{Type: "HowToStep", Text: "Boil"}},
+				RecipeYield:        []string{"4"},
+				TotalTime:          strPtr("PT30M"),
+			},
+			wantScore:   100,
+			wantMissing: []string{},
+			wantStatus:  StatusCompleted,
+		},
+		{
+			name:        "name only",
+			recipe:      &Recipe{Name: "Soup"},
+			wantScore:   0,
+			wantMissing: []string{MissingIngredients, MissingInstructions, MissingYield, MissingTimes},
+			wantStatus:  StatusNeedsReview,
+		},
+		{
+			name: "cook time alone counts as times",
+			recipe: &Recipe{
+				Name:               "Soup",
+				RecipeIngredient:   []string{"water"},
+				RecipeInstructions: []RecipeInstruction{{Type: "HowToStep", Text: "Boil"}},
+				CookTime:           strPtr("PT30M"),
+			},
+			wantScore:   85,
+			wantMissing: []string{MissingYield},
+			wantStatus:  StatusNeedsReview,
+		},
+		{
+			name: "instructions without text are missing",
+			recipe: &Recipe{
+				Name:               "Soup",
+				RecipeIngredient:   []string{"water"},
+				RecipeInstructions: []RecipeInstruction{{Type: "HowToSection", Name: "Empty"}},
+				RecipeYield:        []string{"4"},
+				PrepTime:           strPtr("PT5M"),
+			},
+			wantScore:   65,
+			wantMissing: []string{MissingInstructions},
+			wantStatus:  StatusNeedsReview,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := scoreRecipe(tt.recipe)
+
+			if report.Score != tt.wantScore {
+				t.Errorf("Score = %d, want %d", report.Score, tt.wantScore)
+			}
+			if strings.Join(report.MissingFields, ",") != strings.Join(tt.wantMissing, ",") {
+				t.Errorf("MissingFields = %v, want %v", report.MissingFields, tt.wantMissing)
+			}
+			if report.Status() != tt.wantStatus {
+				t.Errorf("Status() = %q, want %q", report.Status(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCompletenessChecksWeights(t *testing.T) {
+	total := 0
+	for _, check := range completenessChecks {
+		total += check.weight
+	}
+	if total != 100 {
+		t.Errorf("weights sum to %d, want 100", total)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxPageSize caps how much of a page is read. Recipe pages are well below
+// this; anything larger is a download that would exhaust function memory.
+const maxPageSize = 10 << 20
+
+// htmlMediaTypes are the content types routed to the HTML parser
+var htmlMediaTypes = map[string]bool{
+	"text/html":             true,
+	"application/xhtml+xml": true,
+}
+
+// sniffedMediaTypes are missing or generic content types that say nothing
+// about the body, so the body itself is sniffed instead
+var sniffedMediaTypes = map[string]bool{
+	"":                         true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+	"text/plain":               true,
+}
+
+// readHTMLBody reads the body of an HTML response, streaming at most limit
+// bytes, and returns it transcoded to UTF-8. Non-HTML responses are rejected
+// from the Content-Type header before the body is read, or by sniffing the
+// body when the header is generic.
+func readHTMLBody(resp *http.Response, limit int64) (string, error) {
+	mediaType := mediaTypeOf(resp.Header.Get("Content-Type"))
+	if !htmlMediaTypes[mediaType] && !sniffedMediaTypes[mediaType] {
+		return "", &UnsupportedContentError{ContentType: mediaType}
+	}
+	if resp.ContentLength > limit {
+		return "", &UnsupportedContentError{ContentType: mediaType, Limit: limit}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", transportError(err)
+	}
+	if int64(len(body)) > limit {
+		return "", &UnsupportedContentError{ContentType: mediaType, Limit: limit}
+	}
+
+	if !htmlMediaTypes[mediaType] {
+		if sniffed := mediaTypeOf(http.DetectContentType(body)); !htmlMediaTypes[sniffed] {
+			return "", &UnsupportedContentError{ContentType: sniffed}
+		}
+	}
+
+	page, _ := decodeHTML(body, resp.Header.Get("Content-Type"))
+	return page, nil
+}
+
+// mediaTypeOf returns the lowercase media type of a Content-Type value without parameters
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Diagnostics records how a recipe request was processed. It is stored as
+// JSON in the diagnostics column of the recipe request for troubleshooting.
+type Diagnostics struct {
+	mu       sync.Mutex
+	Attempts []FetchAttempt `json:"attempts"`
+}
+
+// FetchAttempt records a single network call made by a strategy
+type FetchAttempt struct {
+	Strategy   string `json:"strategy"`
+	Operation  string `json:"operation"`
+	Attempt    int    `json:"attempt"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewDiagnostics creates empty diagnostics
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{Attempts: []FetchAttempt{}}
+}
+
+// record appends an attempt; safe for concurrent use
+func (d *Diagnostics) record(attempt FetchAttempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Attempts = append(d.Attempts, attempt)
+}
+
+// JSON serializes the diagnostics for the diagnostics column
+func (d *Diagnostics) JSON() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// diagnosticsKey is the context key for the diagnostics of the running strategy
+type diagnosticsKey struct{}
+
+// diagnosticsScope attributes attempts recorded through a context to a strategy
+type diagnosticsScope struct {
+	diagnostics *Diagnostics
+	strategy    string
+}
+
+// withDiagnostics returns a context recording attempts for the named strategy
+func withDiagnostics(ctx context.Context, diagnostics *Diagnostics, strategy string) context.Context {
+	return context.WithValue(ctx, diagnosticsKey{}, diagnosticsScope{diagnostics: diagnostics, strategy: strategy})
+}
+
+// recordAttempt adds an attempt to the diagnostics carried by ctx, if any
+func recordAttempt(ctx context.Context, attempt FetchAttempt) {
+	scope, ok := ctx.Value(diagnosticsKey{}).(diagnosticsScope)
+	if !ok || scope.diagnostics == nil {
+		return
+	}
+	attempt.Strategy = scope.strategy
+	scope.diagnostics.record(attempt)
+}
@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appwrite/sdk-for-go/tablesdb"
+)
+
+// DomainStatsTableID is the table holding the outcomes of each strategy per domain
+const DomainStatsTableID = "domain_stats"
+
+// envRoutingHalfLife overrides DefaultRoutingHalfLife; 0 disables routing
+const envRoutingHalfLife = "FETCH_ROUTING_HALF_LIFE_HOURS"
+
+// DefaultRoutingHalfLife is the time after which past outcomes count half as
+// much, so domains that fix their markup get the cheaper strategies back
+const DefaultRoutingHalfLife = 7 * 24 * time.Hour
+
+// Routing thresholds. A strategy needs minRoutingSamples (decayed) outcomes on
+// a domain before its history is trusted. Below demoteSuccessRate it moves
+// behind the other strategies; below skipSuccessRate it is not tried at all.
+const (
+	minRoutingSamples = 3.0
+	demoteSuccessRate = 0.5
+	skipSuccessRate   = 0.1
+)
+
+// RoutingHalfLifeFromEnv returns DefaultRoutingHalfLife unless overridden from the environment
+func RoutingHalfLifeFromEnv() time.Duration {
+	if hours, ok := envInt(envRoutingHalfLife); ok && hours >= 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return DefaultRoutingHalfLife
+}
+
+// StrategyOutcome is the result of one strategy attempt, recorded for routing
+type StrategyOutcome struct {
+	Strategy string
+	Success  bool
+	// Code classifies a failure
+	Code    ErrorCode
+	Latency time.Duration
+}
+
+// StrategyStats is the decaying history of one strategy on one domain
+type StrategyStats struct {
+	Successes     float64 `json:"successes"`
+	Failures      float64 `json:"failures"`
+	LatencyMs     float64 `json:"latency_ms"` // moving average over all attempts
+	LastErrorCode string  `json:"last_error_code"`
+	UpdatedAt     int64   `json:"updated_at"` // Unix milliseconds
+}
+
+// decayed returns the stats with past outcomes weighted down to now
+func (s StrategyStats) decayed(halfLife time.Duration, now time.Time) StrategyStats {
+	if s.UpdatedAt == 0 || halfLife <= 0 {
+		return s
+	}
+	elapsed := now.UnixMilli() - s.UpdatedAt
+	if elapsed <= 0 {
+		return s
+	}
+	factor := math.Pow(0.5, float64(elapsed)/float64(halfLife.Milliseconds()))
+	s.Successes *= factor
+	s.Failures *= factor
+	return s
+}
+
+// samples returns the weight of the recorded outcomes
+func (s StrategyStats) samples() float64 {
+	return s.Successes + s.Failures
+}
+
+// successRate returns the share of successful outcomes
+func (s StrategyStats) successRate() float64 {
+	if s.samples() == 0 {
+		return 0
+	}
+	return s.Successes / s.samples()
+}
+
+// record adds an outcome to the decayed stats
+func (s StrategyStats) record(outcome StrategyOutcome, halfLife time.Duration, now time.Time) StrategyStats {
+	s = s.decayed(halfLife, now)
+	latencyMs := float64(outcome.Latency.Milliseconds())
+	if s.samples() == 0 {
+		s.LatencyMs = latencyMs
+	} else {
+		s.LatencyMs = 0.7*s.LatencyMs + 0.3*latencyMs
+	}
+	if outcome.Success {
+		s.Successes++
+	} else {
+		s.Failures++
+		s.LastErrorCode = string(outcome.Code)
+	}
+	s.UpdatedAt = now.UnixMilli()
+	return s
+}
+
+// DomainStatsStore holds strategy stats so they are shared across executions
+type DomainStatsStore interface {
+	// Load returns the stats of strategy on domain, zero if there are none
+	Load(ctx context.Context, domain, strategy string) (StrategyStats, error)
+	// Save replaces the stats of strategy on domain
+	Save(ctx context.Context, domain, strategy string, stats StrategyStats) error
+}
+
+// memoryDomainStatsStore keeps stats in memory, shared only within one
+// function instance
+type memoryDomainStatsStore struct {
+	mu    sync.Mutex
+	stats map[string]StrategyStats
+}
+
+// newMemoryDomainStatsStore creates an empty memoryDomainStatsStore
+func newMemoryDomainStatsStore() *memoryDomainStatsStore {
+	return &memoryDomainStatsStore{stats: map[string]StrategyStats{}}
+}
+
+// Load returns the in-memory stats of strategy on domain
+func (s *memoryDomainStatsStore) Load(_ context.Context, domain, strategy string) (StrategyStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats[domain+"|"+strategy], nil
+}
+
+// Save stores the stats of strategy on domain in memory
+func (s *memoryDomainStatsStore) Save(_ context.Context, domain, strategy string, stats StrategyStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[domain+"|"+strategy] = stats
+	return nil
+}
+
+// appwriteDomainStatsStore keeps stats in the domain_stats table, one row
+// per domain and strategy
+type appwriteDomainStatsStore struct {
+	tablesdb *tablesdb.TablesDB
+}
+
+// DomainStatsStore returns a DomainStatsStore backed by the domain_stats table
+func (c *RecipeRequestClient) DomainStatsStore() DomainStatsStore {
+	return &appwriteDomainStatsStore{tablesdb: c.tablesdb}
+}
+
+// Load reads the stats row of strategy on domain
+func (s *appwriteDomainStatsStore) Load(_ context.Context, domain, strategy string) (StrategyStats, error) {
+	var stats StrategyStats
+	row, err := s.tablesdb.GetRow(DatabaseID, DomainStatsTableID, domainStatsRowID(domain, strategy))
+	if isAppwriteNotFound(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, fmt.Errorf("failed to read %s stats for %s: %w", strategy, domain, err)
+	}
+	if err := row.Decode(&stats); err != nil {
+		return stats, fmt.Errorf("failed to decode %s stats for %s: %w", strategy, domain, err)
+	}
+	return stats, nil
+}
+
+// Save upserts the stats row of strategy on domain
+func (s *appwriteDomainStatsStore) Save(_ context.Context, domain, strategy string, stats StrategyStats) error {
+	_, err := s.tablesdb.UpsertRow(DatabaseID, DomainStatsTableID, domainStatsRowID(domain, strategy), s.tablesdb.WithUpsertRowData(map[string]interface{}{
+		"domain":          domain,
+		"strategy":        strategy,
+		"successes":       stats.Successes,
+		"failures":        stats.Failures,
+		"latency_ms":      stats.LatencyMs,
+		"last_error_code": stats.LastErrorCode,
+		"updated_at":      stats.UpdatedAt,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to save %s stats for %s: %w", strategy, domain, err)
+	}
+	return nil
+}
+
+// domainStatsRowID derives a valid row ID (at most 36 characters) from a
+// domain and strategy
+func domainStatsRowID(domain, strategy string) string {
+	sum := sha256.Sum256([]byte(domain + "|" + strategy))
+	return hex.EncodeToString(sum[:16])
+}
+
+// domainKey normalizes a host so www and non-www share their history
+func domainKey(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// inDomain reports whether host is domain or one of its subdomains; domain
+// is expected to be a domainKey
+func inDomain(host, domain string) bool {
+	host = domainKey(strings.TrimSuffix(host, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// DomainRouter learns which strategies work on each domain and adapts the
+// pipeline to it: strategies that mostly fail on a domain are tried last,
+// and those that almost never work are skipped. A nil router keeps the
+// configured order.
+type DomainRouter struct {
+	store    DomainStatsStore
+	halfLife time.Duration
+	logger   *Logger
+	now      func() time.Time
+}
+
+// NewDomainRouter creates a DomainRouter keeping its history in store.
+// A halfLife of 0 disables routing.
+func NewDomainRouter(store DomainStatsStore, halfLife time.Duration, logger *Logger) *DomainRouter {
+	return &DomainRouter{store: store, halfLife: halfLife, logger: logger, now: time.Now}
+}
+
+// enabled reports whether the router adapts anything
+func (r *DomainRouter) enabled() bool {
+	return r != nil && r.halfLife > 0
+}
+
+// Route returns the strategies to run for rawURL, in order. The configured
+// order is kept among strategies without a poor history on the domain. If
+// every strategy would be skipped, or the history can't be read, the
+// configured pipeline runs unchanged.
+func (r *DomainRouter) Route(ctx context.Context, rawURL string, strategies []FetchStrategy) []FetchStrategy {
+	domain := urlDomain(rawURL)
+	if !r.enabled() || domain == "" || len(strategies) < 2 {
+		return strategies
+	}
+
+	now := r.now()
+	var preferred, demoted []FetchStrategy
+	var skipped []string
+	for _, strategy := range strategies {
+		stats, err := r.store.Load(ctx, domain, strategy.Name())
+		if err != nil {
+			r.logWarn("Domain history unavailable, using configured order", map[string]interface{}{"domain": domain, "error": err.Error()})
+			return strategies
+		}
+		stats = stats.decayed(r.halfLife, now)
+
+		switch {
+		case stats.samples() < minRoutingSamples:
+			preferred = append(preferred, strategy)
+		case stats.successRate() < skipSuccessRate:
+			skipped = append(skipped, strategy.Name())
+		case stats.successRate() < demoteSuccessRate:
+			demoted = append(demoted, strategy)
+		default:
+			preferred = append(preferred, strategy)
+		}
+	}
+
+	routed := append(preferred, demoted...)
+	if len(routed) == 0 {
+		return strategies
+	}
+	if len(skipped) > 0 || len(demoted) > 0 {
+		names := make([]string, len(routed))
+		for i, strategy := range routed {
+			names[i] = strategy.Name()
+		}
+		r.logInfo("Routing strategies from domain history", map[string]interface{}{
+			"domain":  domain,
+			"order":   names,
+			"skipped": skipped,
+		})
+	}
+	return routed
+}
+
+// Record adds the outcomes of an execution to the domain's history. Store
+// failures are logged; they never fail the import.
+func (r *DomainRouter) Record(ctx context.Context, rawURL string, outcomes []StrategyOutcome) {
+	domain := urlDomain(rawURL)
+	if !r.enabled() || domain == "" {
+		return
+	}
+
+	now := r.now()
+	for _, outcome := range outcomes {
+		stats, err := r.store.Load(ctx, domain, outcome.Strategy)
+		if err == nil {
+			err = r.store.Save(ctx, domain, outcome.Strategy, stats.record(outcome, r.halfLife, now))
+		}
+		if err != nil {
+			r.logWarn("Error recording domain history", map[string]interface{}{"domain": domain, "error": err.Error()})
+			return
+		}
+	}
+}
+
+// strategyOutcome describes an attempt for routing. Failures that don't say
+// anything about the strategy, such as cancellation by a winning hedge or
+// errors every strategy would hit, are not recorded.
+func strategyOutcome(strategy string, err error, latency time.Duration) (StrategyOutcome, bool) {
+	outcome := StrategyOutcome{Strategy: strategy, Success: err == nil, Latency: latency}
+	if err == nil {
+		return outcome, true
+	}
+	if errors.Is(err, context.Canceled) {
+		return outcome, false
+	}
+	if _, fallback := fallbackFor(err); !fallback {
+		return outcome, false
+	}
+	outcome.Code = classifyFetchError(err).Code
+	return outcome, true
+}
+
+// urlDomain returns the normalized host of rawURL, or "" if it has none
+func urlDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return domainKey(u.Hostname())
+}
+
+// logInfo logs an info message if logger is available
+func (r *DomainRouter) logInfo(msg string, fields map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Info("routing", msg, fields)
+	}
+}
+
+// logWarn logs a warning if logger is available
+func (r *DomainRouter) logWarn(msg string, fields map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Warn("routing", msg, fields)
+	}
+}
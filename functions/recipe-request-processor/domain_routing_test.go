@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrategyStats_Decayed(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	stats := StrategyStats{Successes: 4, Failures: 8, UpdatedAt: now.Add(-48 * time.Hour).UnixMilli()}
+
+	got := stats.decayed(24*time.Hour, now)
+	if math.Abs(got.Successes-1) > 1e-9 || math.Abs(got.Failures-2) > 1e-9 {
+		t.Errorf("decayed() = %+v, want 1 success and 2 failures after two half-lives", got)
+	}
+	if got.successRate() != stats.successRate() {
+		t.Errorf("successRate() = %v, want decay to keep %v", got.successRate(), stats.successRate())
+	}
+}
+
+func TestStrategyStats_Record(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	stats := StrategyStats{}.
+		record(StrategyOutcome{Success: true, Latency: 1000 * time.Millisecond}, time.Hour, now).
+		record(StrategyOutcome{Code: CodeNoRecipe, Latency: 2000 * time.Millisecond}, time.Hour, now)
+
+	if stats.Successes != 1 || stats.Failures != 1 {
+		t.Errorf("record() = %+v, want 1 success and 1 failure", stats)
+	}
+	if stats.LastErrorCode != string(CodeNoRecipe) {
+		t.Errorf("LastErrorCode = %q, want %q", stats.LastErrorCode, CodeNoRecipe)
+	}
+	if stats.LatencyMs != 1300 {
+		t.Errorf("LatencyMs = %v, want 1300", stats.LatencyMs)
+	}
+	if stats.UpdatedAt != now.UnixMilli() {
+		t.Errorf("UpdatedAt = %d, want %d", stats.UpdatedAt, now.UnixMilli())
+	}
+}
+
+func TestDomainRouter_Route(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	history := func(successes, failures float64, age time.Duration) StrategyStats {
+		return StrategyStats{Successes: successes, Failures: failures, UpdatedAt: now.Add(-age).UnixMilli()}
+	}
+
+	tests := []struct {
+		name      string
+		halfLife  time.Duration
+		http      StrategyStats
+		firecrawl StrategyStats
+		want      string
+	}{
+		{
+			name:     "no history keeps configured order",
+			halfLife: time.Hour,
+			want:     "http,firecrawl",
+		},
+		{
+			name:     "too few outcomes keep configured order",
+			halfLife: time.Hour,
+			http:     history(0, 2, 0),
+			want:     "http,firecrawl",
+		},
+		{
+			name:     "mostly failing strategy is demoted",
+			halfLife: time.Hour,
+			http:     history(1, 3, 0),
+			want:     "firecrawl,http",
+		},
+		{
+			name:      "always failing strategy is skipped",
+			halfLife:  time.Hour,
+			http:      history(0, 5, 0),
+			firecrawl: history(5, 0, 0),
+			want:      "firecrawl",
+		},
+		{
+			name:     "decayed history gets the strategy retried",
+			halfLife: time.Hour,
+			http:     history(0, 5, 2*time.Hour),
+			want:     "http,firecrawl",
+		},
+		{
+			name:      "never skips every strategy",
+			halfLife:  time.Hour,
+			http:      history(0, 5, 0),
+			firecrawl: history(0, 5, 0),
+			want:      "http,firecrawl",
+		},
+		{
+			name:     "disabled",
+			halfLife: 0,
+			http:     history(0, 5, 0),
+			want:     "http,firecrawl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryDomainStatsStore()
+			store.Save(context.Background(), "example.com", "http", tt.http)
+			store.Save(context.Background(), "example.com", "firecrawl", tt.firecrawl)
+			router := NewDomainRouter(store, tt.halfLife, nil)
+			router.now = func() time.Time { return now }
+
+			strategies := []FetchStrategy{&fakeStrategy{name: "http"}, &fakeStrategy{name: "firecrawl"}}
+			routed := router.Route(context.Background(), "https://www.Example.com/recipe", strategies)
+
+			var names []string
+			for _, strategy := range routed {
+				names = append(names, strategy.Name())
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("Route() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// failingStatsStore is a DomainStatsStore that is always unavailable
+type failingStatsStore struct{}
+
+func (failingStatsStore) Load(context.Context, string, string) (StrategyStats, error) {
+	return StrategyStats{}, errors.New("unavailable")
+}
+
+func (failingStatsStore) Save(context.Context, string, string, StrategyStats) error {
+	return errors.New("unavailable")
+}
+
+func TestDomainRouter_StoreUnavailable(t *testing.T) {
+	router := NewDomainRouter(failingStatsStore{}, time.Hour, nil)
+	strategies := []FetchStrategy{&fakeStrategy{name: "http"}, &fakeStrategy{name: "firecrawl"}}
+
+	if routed := router.Route(context.Background(), "https://example.com/recipe", strategies); len(routed) != 2 {
+		t.Errorf("Route() returned %d strategies, want the configured 2", len(routed))
+	}
+	// Must not panic or fail
+	router.Record(context.Background(), "https://example.com/recipe", []StrategyOutcome{{Strategy: "http", Success: true}})
+}
+
+func TestStrategyOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantRecord bool
+		wantCode   ErrorCode
+	}{
+		{name: "success", wantRecord: true},
+		{name: "no recipe", err: &NoRecipeError{}, wantRecord: true, wantCode: CodeNoRecipe},
+		{name: "blocked", err: &BlockedError{StatusCode: 403}, wantRecord: true, wantCode: CodeBlocked},
+		{name: "cancelled by a winning hedge", err: context.Canceled},
+		{name: "not found on every strategy", err: &HTTPStatusError{StatusCode: 404}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, ok := strategyOutcome("http", tt.err, time.Second)
+			if ok != tt.wantRecord {
+				t.Fatalf("strategyOutcome() recorded = %v, want %v", ok, tt.wantRecord)
+			}
+			if ok && (outcome.Success != (tt.err == nil) || outcome.Code != tt.wantCode) {
+				t.Errorf("strategyOutcome() = %+v, want code %q", outcome, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestStrategyExecutor_LearnsRouting(t *testing.T) {
+	store := newMemoryDomainStatsStore()
+	router := NewDomainRouter(store, DefaultRoutingHalfLife, nil)
+	now := time.Now()
+	router.now = func() time.Time { return now }
+	http := &fakeStrategy{name: "http", err: &NoRecipeError{}}
+	firecrawl := &fakeStrategy{name: "firecrawl", recipe: &Recipe{Name: "Soup"}}
+
+	for i := 0; i < 5; i++ {
+		executor := NewStrategyExecutor(http, firecrawl).WithRouter(router)
+		if _, err := executor.Execute(context.Background(), "https://example.com/recipe", nil); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+
+	// http failed on the first three imports, then was skipped
+	if http.calls != 3 {
+		t.Errorf("http calls = %d, want 3", http.calls)
+	}
+	if firecrawl.calls != 5 {
+		t.Errorf("firecrawl calls = %d, want 5", firecrawl.calls)
+	}
+	stats, _ := store.Load(context.Background(), "example.com", "http")
+	if stats.LastErrorCode != string(CodeNoRecipe) {
+		t.Errorf("LastErrorCode = %q, want %q", stats.LastErrorCode, CodeNoRecipe)
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPStatusError reports a non-success HTTP status from the fetched page
+type HTTPStatusError struct {
+	StatusCode int
+	// RetryAfter is the wait requested by the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// BlockedError reports that the site refused the request as a suspected bot
+// (403/429 or a bot protection challenge page)
+type BlockedError struct {
+	StatusCode int
+	// RetryAfter is the wait requested by the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked by bot protection (status code: %d)", e.StatusCode)
+}
+
+// NetworkError reports a failure to reach the site, such as a refused
+// connection or a DNS lookup failure
+type NetworkError struct {
+	Err error
+	// HostNotFound is set when DNS reports that the host does not exist
+	HostNotFound bool
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError reports that fetching the page ran out of time
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError reports that the fetched page could not be parsed as HTML
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse HTML: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedContentError reports a response that is not an HTML page or is
+// larger than the page size limit
+type UnsupportedContentError struct {
+	ContentType string
+	// Limit is set when the response exceeded the page size limit
+	Limit int64
+}
+
+func (e *UnsupportedContentError) Error() string {
+	if e.Limit > 0 {
+		return fmt.Sprintf("response larger than %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("unsupported content type %q", e.ContentType)
+}
+
+// statusError returns the typed error for a non-success HTTP response
+func statusError(resp *http.Response) error {
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return &NoRecipeError{Class: PageClassNotFound, HTTPStatus: resp.StatusCode}
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		return &BlockedError{StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	case resp.Header.Get("cf-mitigated") == "challenge":
+		// Cloudflare answers bot challenges with a 503 and this header
+		return &BlockedError{StatusCode: resp.StatusCode}
+	}
+	return &HTTPStatusError{StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+}
+
+// transportError wraps an error from sending a request or reading its body
+// in the matching typed error. Cancellation of the caller and URLs refused by
+// the guarded transport are returned as is.
+func transportError(err error) error {
+	if errors.Is(err, context.Canceled) || errorAs[*DisallowedURLError](err) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Err: err}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &NetworkError{Err: err, HostNotFound: dnsErr.IsNotFound}
+	}
+	return &NetworkError{Err: err}
+}
+
+// fallbackRule decides whether the next strategy is tried after a failure
+type fallbackRule struct {
+	name     string
+	matches  func(err error) bool
+	fallback bool
+}
+
+// fallbackPolicy is checked in order by StrategyExecutor; the first matching
+// rule wins and errors matching no rule stop the executor
+var fallbackPolicy = []fallbackRule{
+	// Internal addresses must not be fetched by any strategy
+	{"disallowed url", errorAs[*DisallowedURLError], false},
+	// Videos, PDFs and huge downloads are not recipe pages for any strategy
+	{"unsupported content", errorAs[*UnsupportedContentError], false},
+	// The site asked bots not to fetch the page; no strategy may scrape it
+	{"robots.txt", errorAs[*RobotsDisallowedError], false},
+	// Other strategies fetch the same host and share its rate limit
+	{"rate limited", errorAs[*RateLimitedError], false},
+	// Pages that don't exist won't have a recipe for any other strategy either
+	{"page does not exist", isDefinitiveNoRecipe, false},
+	{"host does not exist", isHostNotFound, false},
+	// Firecrawl gets past most bot protection
+	{"blocked", errorAs[*BlockedError], true},
+	// Firecrawl can fall back to LLM extraction
+	{"no structured data", errorAs[*NoRecipeError], true},
+	{"timeout", isTimeout, true},
+	{"network", errorAs[*NetworkError], true},
+	{"server error", isServerError, true},
+	{"parse", errorAs[*ParseError], true},
+	{"strategy unavailable", func(err error) bool { return errors.Is(err, ErrStrategyUnavailable) }, true},
+	{"client error", errorAs[*HTTPStatusError], false},
+}
+
+// fallbackFor reports whether the next strategy should be tried after err,
+// along with the name of the deciding rule for logging
+func fallbackFor(err error) (rule string, fallback bool) {
+	for _, r := range fallbackPolicy {
+		if r.matches(err) {
+			return r.name, r.fallback
+		}
+	}
+	return "unclassified", false
+}
+
+// errorAs reports whether err has an error of type T in its chain
+func errorAs[T error](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
+
+// isDefinitiveNoRecipe reports whether err says the page cannot have a recipe
+func isDefinitiveNoRecipe(err error) bool {
+	var noRecipe *NoRecipeError
+	return errors.As(err, &noRecipe) && noRecipe.isDefinitive()
+}
+
+// isTimeout reports whether err is a strategy running out of time
+func isTimeout(err error) bool {
+	return errorAs[*TimeoutError](err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isHostNotFound reports whether err is a DNS lookup for a nonexistent host
+func isHostNotFound(err error) bool {
+	var netErr *NetworkError
+	return errors.As(err, &netErr) && netErr.HostNotFound
+}
+
+// isServerError reports whether err is a 5xx response from the site
+func isServerError(err error) bool {
+	var statusErr *HTTPStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFallbackFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"listing page falls back", &NoRecipeError{Class: PageClassListing}, true},
+		{"not found does not fall back", &NoRecipeError{Class: PageClassNotFound}, false},
+		{"parked domain does not fall back", &NoRecipeError{Class: PageClassParkedDomain}, false},
+		{"blocked falls back", &BlockedError{StatusCode: 403}, true},
+		{"wrapped blocked falls back", fmt.Errorf("fetch: %w", &BlockedError{StatusCode: 429}), true},
+		{"server error falls back", &HTTPStatusError{StatusCode: 502}, true},
+		{"client error does not fall back", &HTTPStatusError{StatusCode: 400}, false},
+		{"timeout falls back", &TimeoutError{Err: context.DeadlineExceeded}, true},
+		{"network error falls back", &NetworkError{Err: errors.New("connection refused")}, true},
+		{"unknown host does not fall back", &NetworkError{Err: errors.New("no such host"), HostNotFound: true}, false},
+		{"rate limited does not fall back", &RateLimitedError{Host: "example.com", Wait: time.Minute}, false},
+		{"unsupported content does not fall back", &UnsupportedContentError{ContentType: "video/mp4"}, false},
+		{"parse error falls back", &ParseError{Err: errors.New("bad html")}, true},
+		{"unavailable strategy falls back", fmt.Errorf("%w: no key", ErrStrategyUnavailable), true},
+		{"unclassified error does not fall back", errors.New("error at byte 403"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := fallbackFor(tt.err); got != tt.want {
+				t.Errorf("fallbackFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		header     http.Header
+		want       error
+	}{
+		{"not found", 404, nil, &NoRecipeError{}},
+		{"gone", 410, nil, &NoRecipeError{}},
+		{"forbidden", 403, nil, &BlockedError{}},
+		{"too many requests", 429, nil, &BlockedError{}},
+		{"cloudflare challenge", 503, http.Header{"Cf-Mitigated": []string{"challenge"}}, &BlockedError{}},
+		{"server error", 500, nil, &HTTPStatusError{}},
+		{"bad request", 400, nil, &HTTPStatusError{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			err := statusError(&http.Response{StatusCode: tt.statusCode, Header: header})
+
+			if got, want := fmt.Sprintf("%T", err), fmt.Sprintf("%T", tt.want); got != want {
+				t.Errorf("statusError() type = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+
+	t.Run("deadline is a timeout", func(t *testing.T) {
+		err := transportError(urlErr(context.DeadlineExceeded))
+		if !errorAs[*TimeoutError](err) {
+			t.Errorf("transportError() = %T, want *TimeoutError", err)
+		}
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		err := transportError(urlErr(&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}))
+		var netErr *NetworkError
+		if !errors.As(err, &netErr) || !netErr.HostNotFound {
+			t.Errorf("transportError() = %#v, want NetworkError with HostNotFound", err)
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		err := transportError(urlErr(errors.New("connection refused")))
+		var netErr *NetworkError
+		if !errors.As(err, &netErr) || netErr.HostNotFound {
+			t.Errorf("transportError() = %#v, want NetworkError", err)
+		}
+	})
+
+	t.Run("cancellation passes through", func(t *testing.T) {
+		err := transportError(urlErr(context.Canceled))
+		if errorAs[*NetworkError](err) || errorAs[*TimeoutError](err) || !errors.Is(err, context.Canceled) {
+			t.Errorf("transportError() = %#v, want unwrapped cancellation", err)
+		}
+	})
+}
+
+func TestStatusError_RetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "2")
+
+	err := statusError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: header})
+
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.RetryAfter != 2*time.Second {
+		t.Errorf("statusError() = %#v, want BlockedError with RetryAfter 2s", err)
+	}
+}
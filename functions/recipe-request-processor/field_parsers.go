@@ -1,6 +1,9 @@
 package handler
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // parseImage parses image field which can be string or array
 func parseImage(imageVal interface{}) []string {
@@ -174,3 +177,34 @@ func parseNutrition(nutritionVal interface{}) *Nutrition {
 
 	return nutrition
 }
+
+// dietLabels are the schema.org RestrictedDiet values
+var dietLabels = []string{
+	"DiabeticDiet",
+	"GlutenFreeDiet",
+	"HalalDiet",
+	"HinduDiet",
+	"KosherDiet",
+	"LowCalorieDiet",
+	"LowFatDiet",
+	"LowLactoseDiet",
+	"LowSaltDiet",
+	"VeganDiet",
+	"VegetarianDiet",
+}
+
+// parseDiets parses suitableForDiet, given as RestrictedDiet names or
+// schema.org URLs, into dietLabels. Unknown values are dropped.
+func parseDiets(val interface{}) []string {
+	var diets []string
+	for _, value := range parseStringOrArray(val) {
+		value = value[strings.LastIndex(value, "/")+1:]
+		for _, label := range dietLabels {
+			if strings.EqualFold(value, label) {
+				diets = append(diets, label)
+				break
+			}
+		}
+	}
+	return diets
+}
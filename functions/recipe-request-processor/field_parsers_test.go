@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -516,3 +517,41 @@ func TestParseStringOrArray(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDiets(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected []string
+	}{
+		{
+			name:     "schema.org URL",
+			input:    "https://schema.org/GlutenFreeDiet",
+			expected: []string{"GlutenFreeDiet"},
+		},
+		{
+			name:     "names in any case",
+			input:    []interface{}{"VeganDiet", "lowsaltdiet"},
+			expected: []string{"VeganDiet", "LowSaltDiet"},
+		},
+		{
+			name:     "unknown values dropped",
+			input:    []interface{}{"http://schema.org/KetoDiet", "Paleo"},
+			expected: nil,
+		},
+		{
+			name:     "nil input",
+			input:    nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseDiets(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseDiets() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
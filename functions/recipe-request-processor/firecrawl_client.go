@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	neturl "net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	firecrawl "github.com/mendableai/firecrawl-go/v2"
 )
@@ -12,20 +20,115 @@ import (
 // ErrNoJSONLD indicates that no JSON-LD structured data was found on the page
 var ErrNoJSONLD = errors.New("no JSON-LD structured data found")
 
+// FirecrawlAPIError reports that the Firecrawl API itself answered with an
+// error, such as running out of credits or an outage
+type FirecrawlAPIError struct {
+	// StatusCode is the status of the API response, 0 if unknown
+	StatusCode int
+	Err        error
+}
+
+func (e *FirecrawlAPIError) Error() string {
+	return fmt.Sprintf("firecrawl API error: %v", e.Err)
+}
+
+func (e *FirecrawlAPIError) Unwrap() error {
+	return e.Err
+}
+
+// firecrawlStatusMessages are the messages the SDK uses for some statuses
+var firecrawlStatusMessages = map[string]int{
+	"Payment Required":      http.StatusPaymentRequired,
+	"Request Timeout":       http.StatusRequestTimeout,
+	"Conflict":              http.StatusConflict,
+	"Internal Server Error": http.StatusInternalServerError,
+}
+
+// firecrawlStatusCode matches the status code in other SDK error messages
+var firecrawlStatusCode = regexp.MustCompile(`Status code (\d{3})`)
+
+// firecrawlAPIError wraps an error returned by the SDK with the status of the
+// API response recorded by statusRecorder. The SDK returns plain errors, so
+// when no status was recorded the message is parsed as a last resort.
+func firecrawlAPIError(err error, statusCode int) error {
+	apiErr := &FirecrawlAPIError{Err: err}
+	if statusCode != 0 && statusCode != http.StatusOK {
+		apiErr.StatusCode = statusCode
+		return apiErr
+	}
+	if match := firecrawlStatusCode.FindStringSubmatch(err.Error()); match != nil {
+		apiErr.StatusCode, _ = strconv.Atoi(match[1])
+		return apiErr
+	}
+	for message, statusCode := range firecrawlStatusMessages {
+		if strings.HasPrefix(err.Error(), message+":") {
+			apiErr.StatusCode = statusCode
+		}
+	}
+	return apiErr
+}
+
+// statusRecorder is an http.RoundTripper remembering the status of the last
+// response, which the SDK only reports in its error messages
+type statusRecorder struct {
+	next   http.RoundTripper
+	status atomic.Int32
+}
+
+// RoundTrip sends req with next and records the response status
+func (r *statusRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err == nil {
+		r.status.Store(int32(resp.StatusCode))
+	}
+	return resp, err
+}
+
+// isFirecrawlFailure reports whether err shows Firecrawl itself failing, as
+// opposed to the scraped site or page. Only these failures trip the circuit
+// breaker.
+func isFirecrawlFailure(err error) bool {
+	var apiErr *FirecrawlAPIError
+	if errors.As(err, &apiErr) {
+		// A rejected URL says nothing about Firecrawl's health
+		return apiErr.StatusCode != http.StatusBadRequest
+	}
+	return errorAs[*NetworkError](err) || errorAs[*TimeoutError](err)
+}
+
 // FirecrawlStrategy implements FetchStrategy using Firecrawl API
 // It uses a hybrid approach:
 // 1. First tries to get HTML and parse JSON-LD (cheaper)
-// 2. Falls back to LLM extraction if no JSON-LD found (more expensive but works for any page)
+// 2. Then parses the markdown of the same scrape for ingredient and step lists (free)
+// 3. Falls back to LLM extraction if neither finds a recipe (more expensive but works for any page)
 type FirecrawlStrategy struct {
 	apiKey string
+	// apiURL overrides the Firecrawl API URL; empty uses FIRECRAWL_API_URL or
+	// the SDK default
+	apiURL string
 	logger *Logger
+	retry  RetryPolicy
+	// limiter spaces out scrapes of the same host; Firecrawl fetches the
+	// site just like the HTTP client does
+	limiter *HostRateLimiter
+	// allowLLM enables the LLM extraction fallback; pipelines may disable it
+	// to control cost
+	allowLLM bool
+	// breaker short-circuits the strategy while Firecrawl keeps failing
+	breaker *CircuitBreaker
+	// meter records every call and enforces the per-user LLM quota
+	meter *UsageMeter
 }
 
-// NewFirecrawlStrategy creates a new FirecrawlStrategy with the API key from environment
-func NewFirecrawlStrategy(logger *Logger) *FirecrawlStrategy {
+// NewFirecrawlStrategy creates a new FirecrawlStrategy with the given rate
+// limiter (nil for none) and the API key and retry policy from environment
+func NewFirecrawlStrategy(logger *Logger, limiter *HostRateLimiter) *FirecrawlStrategy {
 	return &FirecrawlStrategy{
-		apiKey: os.Getenv("FIRECRAWL_API_KEY"),
-		logger: logger,
+		apiKey:   os.Getenv("FIRECRAWL_API_KEY"),
+		logger:   logger,
+		retry:    RetryPolicyFromEnv(),
+		limiter:  limiter,
+		allowLLM: true,
 	}
 }
 
@@ -34,11 +137,6 @@ func (s *FirecrawlStrategy) Name() string {
 	return "Firecrawl"
 }
 
-// CanRetry returns false - Firecrawl is typically the last resort
-func (s *FirecrawlStrategy) CanRetry(err error) bool {
-	return false
-}
-
 // logInfo logs an info message
 func (s *FirecrawlStrategy) logInfo(msg string, fields ...map[string]interface{}) {
 	if s.logger != nil {
@@ -62,21 +160,39 @@ func (s *FirecrawlStrategy) logError(msg string, fields ...map[string]interface{
 }
 
 // Fetch uses Firecrawl API to fetch the page and extract recipe data
-// It first tries HTML parsing for JSON-LD, then falls back to LLM extraction
-func (s *FirecrawlStrategy) Fetch(url string) (*Recipe, error) {
+// It first tries HTML parsing for JSON-LD, then the page markdown, then falls
+// back to LLM extraction. All steps share the deadline of ctx. While the
+// circuit breaker is open, it fails right away with a CircuitOpenError.
+func (s *FirecrawlStrategy) Fetch(ctx context.Context, url string) (*Recipe, error) {
 	if s.apiKey == "" {
-		return nil, fmt.Errorf("FIRECRAWL_API_KEY environment variable is not set")
+		return nil, fmt.Errorf("%w: FIRECRAWL_API_KEY environment variable is not set", ErrStrategyUnavailable)
+	}
+	if err := s.breaker.Allow(ctx); err != nil {
+		s.logError("Firecrawl short-circuited", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
 	}
 
+	recipe, err := s.fetch(ctx, url)
+	// Calls cancelled by the executor say nothing about Firecrawl
+	if !errors.Is(err, context.Canceled) {
+		s.breaker.Record(context.WithoutCancel(ctx), isFirecrawlFailure(err))
+	}
+	return recipe, err
+}
+
+// fetch runs the HTML and markdown steps and, if needed and allowed, the LLM step
+func (s *FirecrawlStrategy) fetch(ctx context.Context, url string) (*Recipe, error) {
 	// Initialize Firecrawl client
-	app, err := firecrawl.NewFirecrawlApp(s.apiKey, "")
+	app, err := firecrawl.NewFirecrawlApp(s.apiKey, s.apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Firecrawl: %w", err)
+		return nil, fmt.Errorf("%w: failed to initialize Firecrawl: %v", ErrStrategyUnavailable, err)
 	}
 
 	// Step 1: Try to get HTML and parse JSON-LD (cheaper approach)
 	s.logInfo("Attempting HTML+JSON-LD extraction")
-	recipe, err := s.fetchWithHTML(app, url)
+	recipe, page, err := s.fetchWithHTML(ctx, app, url)
 	if err == nil && recipe != nil {
 		s.logInfo("Recipe extracted via HTML+JSON-LD parsing", map[string]interface{}{
 			"method": "json-ld",
@@ -85,7 +201,15 @@ func (s *FirecrawlStrategy) Fetch(url string) (*Recipe, error) {
 	}
 
 	// Log why HTML extraction failed
+	htmlErr := err
 	if err != nil {
+		var noRecipe *NoRecipeError
+		if errors.As(err, &noRecipe) && noRecipe.isDefinitive() {
+			s.logInfo("HTML extraction: Page has no recipe, skipping LLM extraction", map[string]interface{}{
+				"page_class": noRecipe.Class,
+			})
+			return nil, err
+		}
 		if errors.Is(err, ErrNoJSONLD) {
 			s.logInfo("HTML extraction: No JSON-LD found on page")
 		} else {
@@ -98,70 +222,124 @@ func (s *FirecrawlStrategy) Fetch(url string) (*Recipe, error) {
 		s.logInfo("HTML extraction: Recipe was nil")
 	}
 
-	// Step 2: Fall back to LLM extraction (for sites without JSON-LD)
+	// Step 2: Parse the markdown requested along with the HTML
+	if page != nil {
+		if recipe := recipeFromMarkdown(page); recipe != nil {
+			s.logInfo("Recipe extracted via markdown parsing", map[string]interface{}{
+				"method": "markdown",
+			})
+			return recipe, nil
+		}
+		s.logInfo("Markdown parsing: No ingredients and steps found")
+	}
+
+	if !s.allowLLM {
+		s.logInfo("LLM extraction disabled by the pipeline configuration")
+		return nil, htmlErr
+	}
+
+	// Each user gets a limited number of LLM extractions; past that the
+	// recipe has to be entered manually
+	if err := s.meter.AllowExtraction(ctx); err != nil {
+		s.logInfo("LLM extraction skipped", map[string]interface{}{
+			"reason": err.Error(),
+		})
+		return nil, errors.Join(htmlErr, err)
+	}
+
+	// Step 3: Fall back to LLM extraction (for sites without JSON-LD)
 	s.logInfo("Falling back to LLM extraction")
-	recipe, err = s.fetchWithLLMExtraction(app, url)
+	recipe, err = s.fetchWithLLMExtraction(ctx, app, url)
 	if err == nil && recipe != nil {
 		s.logInfo("Recipe extracted via LLM", map[string]interface{}{
 			"method": "llm",
 		})
 	}
+	if err != nil && htmlErr != nil {
+		// Keep the page classification from the HTML step alongside the LLM failure
+		return nil, errors.Join(htmlErr, err)
+	}
 	return recipe, err
 }
 
-// fetchWithHTML fetches the page HTML and parses JSON-LD
-func (s *FirecrawlStrategy) fetchWithHTML(app *firecrawl.FirecrawlApp, url string) (*Recipe, error) {
+// fetchWithHTML fetches the page HTML and parses JSON-LD. The markdown of the
+// page is requested in the same scrape at no extra cost and returned with
+// the scraped page for the markdown step.
+func (s *FirecrawlStrategy) fetchWithHTML(ctx context.Context, app *firecrawl.FirecrawlApp, url string) (*Recipe, *firecrawl.FirecrawlDocument, error) {
 	// Request HTML format with cache bypass (maxAge=0 forces fresh scrape)
 	maxAge := 0
 	params := &firecrawl.ScrapeParams{
-		Formats: []string{"rawHtml"},
+		Formats: []string{"rawHtml", "markdown"},
 		MaxAge:  &maxAge,
 	}
 	s.logInfo("Fetching HTML with cache bypass", map[string]interface{}{
 		"max_age": maxAge,
 	})
 
-	result, err := app.ScrapeURL(url, params)
+	result, err := withRetry(ctx, s.retry, "scrape_html", s.logInfo, func() (*firecrawl.FirecrawlDocument, error) {
+		if err := s.waitForHost(ctx, url); err != nil {
+			return nil, err
+		}
+		doc, err := scrapeURL(ctx, app, url, params)
+		s.meter.Record(ctx, UsageScrape, err == nil)
+		return doc, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to scrape URL with Firecrawl: %w", err)
+		return nil, nil, fmt.Errorf("failed to scrape URL with Firecrawl: %w", err)
 	}
 
 	if result == nil {
-		return nil, fmt.Errorf("no result from Firecrawl")
+		return nil, nil, fmt.Errorf("no result from Firecrawl")
 	}
 
 	// Extract recipe from HTML
 	recipe, err := extractRecipeFromHTML(result.RawHTML, s.logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, result, &ParseError{Err: err}
 	}
 
 	if recipe == nil {
-		return nil, ErrNoJSONLD
+		statusCode := 0
+		if result.Metadata != nil && result.Metadata.StatusCode != nil {
+			statusCode = *result.Metadata.StatusCode
+		}
+		return nil, result, &NoRecipeError{Class: classifyPage(result.RawHTML, statusCode), HTTPStatus: statusCode}
 	}
 
-	return recipe, nil
+	return recipe, result, nil
+}
+
+// recipeFromMarkdown parses the markdown of a scraped page. The name and
+// image come from the page metadata when the markdown has no title or image.
+func recipeFromMarkdown(page *firecrawl.FirecrawlDocument) *Recipe {
+	recipe := parseMarkdownRecipe(page.Markdown)
+	if recipe == nil {
+		return nil
+	}
+	if meta := page.Metadata; meta != nil {
+		if recipe.Name == "" && meta.OGTitle != nil && len(*meta.OGTitle) > 0 {
+			recipe.Name = sanitizeText((*meta.OGTitle)[0])
+		}
+		if recipe.Name == "" && meta.Title != nil {
+			recipe.Name = sanitizeText(*meta.Title)
+		}
+		if meta.OGImage != nil && len(*meta.OGImage) > 0 {
+			recipe.Image = []string{(*meta.OGImage)[0]}
+		}
+	}
+	if recipe.Name == "" {
+		return nil
+	}
+	attributeRecipe(recipe, SourceMarkdown)
+	return recipe
 }
 
 // fetchWithLLMExtraction uses Firecrawl's LLM extraction to get structured recipe data
-func (s *FirecrawlStrategy) fetchWithLLMExtraction(app *firecrawl.FirecrawlApp, url string) (*Recipe, error) {
+func (s *FirecrawlStrategy) fetchWithLLMExtraction(ctx context.Context, app *firecrawl.FirecrawlApp, url string) (*Recipe, error) {
 	// Define JSON schema for recipe extraction
 	jsonSchema := buildRecipeExtractionSchema()
 
-	// Create a prompt to help the LLM extract recipe data
-	prompt := `
-Extract the complete recipe data from this page.
-Focus on the main recipe content and ignore advertisements, related recipes, and sidebar content.
-
-CRITICAL - Image selection rules (in order of priority):
-1. BEST: A close-up overhead shot of ONLY the finished dish on a plate/bowl - no people visible at all
-2. ACCEPTABLE: A food-focused shot where the dish is the main subject
-3. REJECT: Any image showing a person, chef, hands, arms, faces, or human body parts holding or presenting food
-4. REJECT: Favicons, logos, avatars, icons, profile photos, or thumbnails smaller than 400px
-
-If multiple food images exist, choose the one showing ONLY the food without any people.
-Extract all available fields including instructions, times, servings, nutrition, and author information when present.
-`
+	prompt := recipeExtractionPrompt
 
 	// Use scrape with JSON extraction options (v2 SDK feature)
 	params := &firecrawl.ScrapeParams{
@@ -172,21 +350,120 @@ Extract all available fields including instructions, times, servings, nutrition,
 		},
 	}
 
-	result, err := app.ScrapeURL(url, params)
+	result, err := withRetry(ctx, s.retry, "scrape_llm", s.logInfo, func() (*firecrawl.FirecrawlDocument, error) {
+		if err := s.waitForHost(ctx, url); err != nil {
+			return nil, err
+		}
+		doc, err := scrapeURL(ctx, app, url, params)
+		s.meter.Record(ctx, UsageExtract, err == nil)
+		return doc, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract recipe with LLM: %w", err)
 	}
 
 	if result == nil || result.JSON == nil {
-		return nil, fmt.Errorf("no data extracted from page")
+		return nil, fmt.Errorf("%w: no data extracted from page", ErrExtractionFailed)
 	}
 
 	// Parse the extracted data into Recipe struct
 	return parseExtractedRecipe(result.JSON)
 }
 
-// buildRecipeExtractionSchema returns the JSON schema for recipe extraction
+// waitForHost waits for the rate limit of the host being scraped
+func (s *FirecrawlStrategy) waitForHost(ctx context.Context, url string) error {
+	page, err := neturl.Parse(url)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	return s.limiter.Wait(ctx, page.Hostname())
+}
+
+// scrapeURL runs a Firecrawl scrape bounded by ctx. The SDK has no context
+// support, so the time left is applied both as the HTTP client timeout and as
+// Firecrawl's own scrape timeout, and the call is abandoned once ctx is done.
+func scrapeURL(ctx context.Context, app *firecrawl.FirecrawlApp, url string, params *firecrawl.ScrapeParams) (*firecrawl.FirecrawlDocument, error) {
+	// Each call gets its own client recording the API response status
+	call := *app
+	client := *app.Client
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	recorder := &statusRecorder{next: transport}
+	client.Transport = recorder
+	call.Client = &client
+
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, &TimeoutError{Err: context.DeadlineExceeded}
+		}
+		call.Client.Timeout = remaining
+		timeoutMs := int(remaining.Milliseconds())
+		params.Timeout = &timeoutMs
+	}
+
+	type scrapeResult struct {
+		doc *firecrawl.FirecrawlDocument
+		err error
+	}
+	done := make(chan scrapeResult, 1)
+	go func() {
+		doc, err := call.ScrapeURL(url, params)
+		done <- scrapeResult{doc, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, transportError(ctx.Err())
+	case result := <-done:
+		// Connection failures are typed so they can be retried
+		var urlErr *neturl.Error
+		if errors.As(result.err, &urlErr) {
+			return nil, transportError(result.err)
+		}
+		if result.err != nil {
+			return nil, firecrawlAPIError(result.err, int(recorder.status.Load()))
+		}
+		return result.doc, nil
+	}
+}
+
+// recipeExtractionPrompt guides LLM extraction along with
+// buildRecipeExtractionSchema
+const recipeExtractionPrompt = `
+Extract the complete recipe data from this page.
+Focus on the main recipe content and ignore advertisements, related recipes, and sidebar content.
+
+CRITICAL - Image selection rules (in order of priority):
+1. BEST: A close-up overhead shot of ONLY the finished dish on a plate/bowl - no people visible at all
+2. ACCEPTABLE: A food-focused shot where the dish is the main subject
+3. REJECT: Any image showing a person, chef, hands, arms, faces, or human body parts holding or presenting food
+4. REJECT: Favicons, logos, avatars, icons, profile photos, or thumbnails smaller than 400px
+
+If multiple food images exist, choose the one showing ONLY the food without any people.
+Extract all available fields including instructions, times, servings, nutrition, and author information when present.
+`
+
+// buildRecipeExtractionSchema returns the JSON schema for recipe extraction.
+// It mirrors the Recipe type so LLM imports carry the same detail as JSON-LD
+// ones; ingredients and instructions are grouped the way the page groups them.
 func buildRecipeExtractionSchema() map[string]any {
+	stringArray := func(description string) map[string]any {
+		return map[string]any{
+			"type":        "array",
+			"items":       map[string]string{"type": "string"},
+			"description": description,
+		}
+	}
+	nutrient := func(description string) map[string]any {
+		return map[string]any{
+			"type":        "string",
+			"description": description,
+		}
+	}
+
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
@@ -198,10 +475,7 @@ func buildRecipeExtractionSchema() map[string]any {
 				"type":        "string",
 				"description": "A brief description of the recipe",
 			},
-			"image": map[string]any{
-				"type":        "string",
-				"description": "URL of an overhead or close-up photo showing ONLY the finished dish with NO people, hands, arms, chefs, or faces visible. Must show just the food on a plate/bowl. NEVER select images with humans in them.",
-			},
+			"image": stringArray("URLs of overhead or close-up photos showing ONLY the finished dish with NO people, hands, arms, chefs, or faces visible, best first. Must show just the food on a plate/bowl. NEVER select images with humans in them."),
 			"prepTime": map[string]any{
 				"type":        "string",
 				"description": "Preparation time (e.g., '15 minutes' or 'PT15M')",
@@ -215,46 +489,90 @@ func buildRecipeExtractionSchema() map[string]any {
 				"description": "Total time (e.g., '45 minutes' or 'PT45M')",
 			},
 			"recipeYield": map[string]any{
-				"type":        "array",
-				"items":       map[string]string{"type": "string"},
-				"description": "Number of servings or yield (e.g., '4 servings', '12 cookies')",
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"amount": map[string]any{
+							"type":        "number",
+							"description": "How many (e.g., 4 or 12)",
+						},
+						"unit": map[string]any{
+							"type":        "string",
+							"description": "What is counted (e.g., 'servings', 'cookies', 'loaf')",
+						},
+					},
+				},
+				"description": "Number of servings or yield, one entry per way the page states it",
 			},
 			"recipeIngredient": map[string]any{
-				"type":        "array",
-				"items":       map[string]string{"type": "string"},
-				"description": "List of ingredients with quantities",
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name": map[string]any{
+							"type":        "string",
+							"description": "Group heading (e.g., 'For the sauce'); empty for a recipe with a single list",
+						},
+						"ingredients": stringArray("Ingredients with quantities, in page order"),
+					},
+					"required": []string{"ingredients"},
+				},
+				"description": "Ingredient groups as headed on the page, or a single unnamed group",
 			},
 			"recipeInstructions": map[string]any{
 				"type": "array",
 				"items": map[string]any{
-					"type":        "string",
-					"description": "A single step in the cooking instructions",
+					"type": "object",
+					"properties": map[string]any{
+						"name": map[string]any{
+							"type":        "string",
+							"description": "Section heading (e.g., 'Make the dough'); empty for a recipe with a single list",
+						},
+						"steps": stringArray("A single step of the cooking instructions per entry, in order"),
+					},
+					"required": []string{"steps"},
 				},
-				"description": "Step-by-step cooking instructions",
+				"description": "Instruction sections as headed on the page, or a single unnamed section",
 			},
 			"author": map[string]any{
-				"type":        "string",
-				"description": "Author or creator of the recipe",
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+					"url":  map[string]any{"type": "string"},
+				},
+				"description": "Author or creator of the recipe, with a link to their page if given",
 			},
-			"recipeCategory": map[string]any{
+			"recipeCategory": stringArray("Category/categories (e.g., 'Dessert', 'Main Course')"),
+			"recipeCuisine":  stringArray("Cuisine type(s) (e.g., 'Italian', 'Mexican')"),
+			"keywords":       stringArray("Tags or keywords the page gives the recipe"),
+			"suitableForDiet": map[string]any{
 				"type":        "array",
-				"items":       map[string]string{"type": "string"},
-				"description": "Category/categories (e.g., 'Dessert', 'Main Course')",
+				"items":       map[string]any{"type": "string", "enum": dietLabels},
+				"description": "Diets the page states the recipe is suitable for; leave empty rather than guess",
 			},
-			"recipeCuisine": map[string]any{
-				"type":        "array",
-				"items":       map[string]string{"type": "string"},
-				"description": "Cuisine type(s) (e.g., 'Italian', 'Mexican')",
+			"datePublished": map[string]any{
+				"type":        "string",
+				"description": "Publication date shown on the page (ISO 8601)",
+			},
+			"dateModified": map[string]any{
+				"type":        "string",
+				"description": "Last updated date shown on the page (ISO 8601)",
 			},
 			"nutrition": map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"calories":            map[string]string{"type": "string"},
-					"fatContent":          map[string]string{"type": "string"},
-					"carbohydrateContent": map[string]string{"type": "string"},
-					"proteinContent":      map[string]string{"type": "string"},
+					"calories":            nutrient("Calories with unit (e.g., '320 kcal')"),
+					"fatContent":          nutrient("Fat with unit (e.g., '12 g')"),
+					"saturatedFatContent": nutrient("Saturated fat with unit"),
+					"cholesterolContent":  nutrient("Cholesterol with unit (e.g., '30 mg')"),
+					"sodiumContent":       nutrient("Sodium with unit (e.g., '400 mg')"),
+					"carbohydrateContent": nutrient("Carbohydrates with unit"),
+					"fiberContent":        nutrient("Fiber with unit"),
+					"sugarContent":        nutrient("Sugar with unit"),
+					"proteinContent":      nutrient("Protein with unit"),
 				},
-				"description": "Nutritional information",
+				"description": "Nutritional information per serving",
 			},
 		},
 		"required": []string{"name", "recipeIngredient", "recipeInstructions"},
@@ -263,33 +581,88 @@ func buildRecipeExtractionSchema() map[string]any {
 
 // ExtractedRecipe represents the LLM-extracted recipe data
 type ExtractedRecipe struct {
-	Name               string             `json:"name"`
-	Description        string             `json:"description"`
-	Image              string             `json:"image"`
-	PrepTime           string             `json:"prepTime"`
-	CookTime           string             `json:"cookTime"`
-	TotalTime          string             `json:"totalTime"`
-	RecipeYield        []string           `json:"recipeYield"`
-	RecipeIngredient   []string           `json:"recipeIngredient"`
-	RecipeInstructions []string           `json:"recipeInstructions"`
-	Author             string             `json:"author"`
-	RecipeCategory     []string           `json:"recipeCategory"`
-	RecipeCuisine      []string           `json:"recipeCuisine"`
-	Nutrition          ExtractedNutrition `json:"nutrition"`
+	Name               string                        `json:"name"`
+	Description        string                        `json:"description"`
+	Image              []string                      `json:"image"`
+	PrepTime           string                        `json:"prepTime"`
+	CookTime           string                        `json:"cookTime"`
+	TotalTime          string                        `json:"totalTime"`
+	RecipeYield        []ExtractedYield              `json:"recipeYield"`
+	RecipeIngredient   []ExtractedIngredientGroup    `json:"recipeIngredient"`
+	RecipeInstructions []ExtractedInstructionSection `json:"recipeInstructions"`
+	Author             ExtractedAuthor               `json:"author"`
+	RecipeCategory     []string                      `json:"recipeCategory"`
+	RecipeCuisine      []string                      `json:"recipeCuisine"`
+	Keywords           []string                      `json:"keywords"`
+	SuitableForDiet    []string                      `json:"suitableForDiet"`
+	DatePublished      string                        `json:"datePublished"`
+	DateModified       string                        `json:"dateModified"`
+	Nutrition          ExtractedNutrition            `json:"nutrition"`
+}
+
+// ExtractedYield represents one LLM-extracted yield, such as 4 servings
+type ExtractedYield struct {
+	Amount float64 `json:"amount"`
+	Unit   string  `json:"unit"`
+}
+
+// ExtractedIngredientGroup represents LLM-extracted ingredients under one heading
+type ExtractedIngredientGroup struct {
+	Name        string   `json:"name"`
+	Ingredients []string `json:"ingredients"`
+}
+
+// ExtractedInstructionSection represents LLM-extracted steps under one heading
+type ExtractedInstructionSection struct {
+	Name  string   `json:"name"`
+	Steps []string `json:"steps"`
+}
+
+// ExtractedAuthor represents the LLM-extracted author
+type ExtractedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
 }
 
 // ExtractedNutrition represents LLM-extracted nutrition data
 type ExtractedNutrition struct {
 	Calories            string `json:"calories"`
 	FatContent          string `json:"fatContent"`
+	SaturatedFatContent string `json:"saturatedFatContent"`
+	CholesterolContent  string `json:"cholesterolContent"`
+	SodiumContent       string `json:"sodiumContent"`
 	CarbohydrateContent string `json:"carbohydrateContent"`
+	FiberContent        string `json:"fiberContent"`
+	SugarContent        string `json:"sugarContent"`
 	ProteinContent      string `json:"proteinContent"`
 }
 
+// flattenIngredientGroups merges ingredient groups into the single list the
+// Recipe type keeps. When there are several groups, each named one is headed
+// by its name as a line ending in a colon, e.g. "For the sauce:", the way
+// JSON-LD recipes list grouped ingredients.
+func flattenIngredientGroups(groups []ExtractedIngredientGroup) []string {
+	var nonEmpty []ExtractedIngredientGroup
+	for _, group := range groups {
+		if group.Ingredients = nonEmptyStrings(group.Ingredients); len(group.Ingredients) > 0 {
+			nonEmpty = append(nonEmpty, group)
+		}
+	}
+
+	var ingredients []string
+	for _, group := range nonEmpty {
+		if name := strings.TrimSuffix(sanitizeText(group.Name), ":"); name != "" && len(nonEmpty) > 1 {
+			ingredients = append(ingredients, name+":")
+		}
+		ingredients = append(ingredients, group.Ingredients...)
+	}
+	return ingredients
+}
+
 // parseExtractedRecipe converts Firecrawl's JSON extraction result to a Recipe struct
 func parseExtractedRecipe(data map[string]any) (*Recipe, error) {
 	if data == nil {
-		return nil, fmt.Errorf("no data extracted from page")
+		return nil, fmt.Errorf("%w: no data extracted from page", ErrExtractionFailed)
 	}
 
 	// Marshal the data back to JSON and unmarshal into our struct
@@ -300,12 +673,12 @@ func parseExtractedRecipe(data map[string]any) (*Recipe, error) {
 
 	var extracted ExtractedRecipe
 	if err := json.Unmarshal(dataBytes, &extracted); err != nil {
-		return nil, fmt.Errorf("failed to parse extracted recipe: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse extracted recipe: %v", ErrExtractionFailed, err)
 	}
 
 	// Validate required fields
 	if extracted.Name == "" {
-		return nil, fmt.Errorf("extracted recipe missing required field: name")
+		return nil, fmt.Errorf("%w: extracted recipe missing required field: name", ErrExtractionFailed)
 	}
 
 	// Convert to Recipe struct
@@ -315,75 +688,83 @@ func parseExtractedRecipe(data map[string]any) (*Recipe, error) {
 		Name:    extracted.Name,
 	}
 
-	// Handle image (convert single string to array)
-	if extracted.Image != "" {
-		recipe.Image = []string{extracted.Image}
-	}
+	recipe.Image = nonEmptyStrings(extracted.Image)
 
 	// Set optional string fields
-	if extracted.Description != "" {
-		recipe.Description = &extracted.Description
-	}
-	if extracted.PrepTime != "" {
-		recipe.PrepTime = &extracted.PrepTime
-	}
-	if extracted.CookTime != "" {
-		recipe.CookTime = &extracted.CookTime
-	}
-	if extracted.TotalTime != "" {
-		recipe.TotalTime = &extracted.TotalTime
-	}
-	if len(extracted.RecipeYield) > 0 {
-		recipe.RecipeYield = extracted.RecipeYield
-	}
-	if len(extracted.RecipeCategory) > 0 {
-		recipe.RecipeCategory = extracted.RecipeCategory
-	}
-	if len(extracted.RecipeCuisine) > 0 {
-		recipe.RecipeCuisine = extracted.RecipeCuisine
+	recipe.Description = stringPtr(extracted.Description)
+	recipe.PrepTime = stringPtr(extracted.PrepTime)
+	recipe.CookTime = stringPtr(extracted.CookTime)
+	recipe.TotalTime = stringPtr(extracted.TotalTime)
+	recipe.DatePublished = stringPtr(extracted.DatePublished)
+	recipe.DateModified = stringPtr(extracted.DateModified)
+	recipe.RecipeCategory = nonEmptyStrings(extracted.RecipeCategory)
+	recipe.RecipeCuisine = nonEmptyStrings(extracted.RecipeCuisine)
+	if keywords := nonEmptyStrings(extracted.Keywords); len(keywords) > 0 {
+		recipe.Keywords = stringPtr(strings.Join(keywords, ", "))
+	}
+	recipe.SuitableForDiet = parseDiets(extracted.SuitableForDiet)
+
+	// Render yields the way JSON-LD states them, e.g. "4 servings"
+	for _, yield := range extracted.RecipeYield {
+		amount := ""
+		if yield.Amount > 0 {
+			amount = strconv.FormatFloat(yield.Amount, 'f', -1, 64)
+		}
+		if text := sanitizeText(amount + " " + yield.Unit); text != "" {
+			recipe.RecipeYield = append(recipe.RecipeYield, text)
+		}
 	}
 
-	// Set ingredients
-	recipe.RecipeIngredient = extracted.RecipeIngredient
+	recipe.RecipeIngredient = flattenIngredientGroups(extracted.RecipeIngredient)
 
-	// Convert instructions from strings to RecipeInstruction
-	if len(extracted.RecipeInstructions) > 0 {
-		recipe.RecipeInstructions = make([]RecipeInstruction, len(extracted.RecipeInstructions))
-		for i, step := range extracted.RecipeInstructions {
-			recipe.RecipeInstructions[i] = RecipeInstruction{
-				Type: "HowToStep",
-				Text: step,
-			}
+	// Named sections become HowToSections, an unnamed one plain HowToSteps
+	for _, section := range extracted.RecipeInstructions {
+		var steps []RecipeInstruction
+		for _, step := range nonEmptyStrings(section.Steps) {
+			steps = append(steps, RecipeInstruction{Type: "HowToStep", Text: step})
+		}
+		if len(steps) == 0 {
+			continue
+		}
+		if name := sanitizeText(section.Name); name != "" {
+			recipe.RecipeInstructions = append(recipe.RecipeInstructions, RecipeInstruction{
+				Type:            "HowToSection",
+				Name:            name,
+				ItemListElement: steps,
+			})
+		} else {
+			recipe.RecipeInstructions = append(recipe.RecipeInstructions, steps...)
 		}
 	}
 
 	// Set author
-	if extracted.Author != "" {
+	if name := sanitizeText(extracted.Author.Name); name != "" {
 		recipe.Author = &Person{
 			Type: "Person",
-			Name: extracted.Author,
+			Name: name,
+			URL:  extracted.Author.URL,
 		}
 	}
 
 	// Set nutrition
-	if extracted.Nutrition.Calories != "" || extracted.Nutrition.FatContent != "" ||
-		extracted.Nutrition.CarbohydrateContent != "" || extracted.Nutrition.ProteinContent != "" {
-		recipe.Nutrition = &Nutrition{
-			Type: "NutritionInformation",
-		}
-		if extracted.Nutrition.Calories != "" {
-			recipe.Nutrition.Calories = &extracted.Nutrition.Calories
-		}
-		if extracted.Nutrition.FatContent != "" {
-			recipe.Nutrition.FatContent = &extracted.Nutrition.FatContent
-		}
-		if extracted.Nutrition.CarbohydrateContent != "" {
-			recipe.Nutrition.CarbohydrateContent = &extracted.Nutrition.CarbohydrateContent
-		}
-		if extracted.Nutrition.ProteinContent != "" {
-			recipe.Nutrition.ProteinContent = &extracted.Nutrition.ProteinContent
-		}
-	}
+	nutrition := &Nutrition{
+		Type:                "NutritionInformation",
+		Calories:            stringPtr(extracted.Nutrition.Calories),
+		FatContent:          stringPtr(extracted.Nutrition.FatContent),
+		SaturatedFatContent: stringPtr(extracted.Nutrition.SaturatedFatContent),
+		CholesterolContent:  stringPtr(extracted.Nutrition.CholesterolContent),
+		SodiumContent:       stringPtr(extracted.Nutrition.SodiumContent),
+		CarbohydrateContent: stringPtr(extracted.Nutrition.CarbohydrateContent),
+		FiberContent:        stringPtr(extracted.Nutrition.FiberContent),
+		SugarContent:        stringPtr(extracted.Nutrition.SugarContent),
+		ProteinContent:      stringPtr(extracted.Nutrition.ProteinContent),
+	}
+	if *nutrition != (Nutrition{Type: nutrition.Type}) {
+		recipe.Nutrition = nutrition
+	}
+
+	// Every field was produced by the LLM
+	attributeRecipe(recipe, SourceLLM)
 
 	return recipe, nil
 }
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -52,13 +55,13 @@ func TestFirecrawlStrategy_RawHTMLWithJSONLD(t *testing.T) {
 		},
 	}
 
-	strategy := NewFirecrawlStrategy(nil)
+	strategy := NewFirecrawlStrategy(nil, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Logf("Testing URL: %s", tt.url)
 
-			recipe, err := strategy.Fetch(tt.url)
+			recipe, err := strategy.Fetch(context.Background(), tt.url)
 			if err != nil {
 				t.Fatalf("Failed to fetch recipe: %v", err)
 			}
@@ -178,9 +181,9 @@ func TestFirecrawlStrategy_LLMExtractionFallback(t *testing.T) {
 	url := "https://alfiecooks.substack.com/p/caramelised-onion-sun-dried-tomato"
 	t.Logf("Testing LLM extraction fallback with URL: %s", url)
 
-	strategy := NewFirecrawlStrategy(nil)
+	strategy := NewFirecrawlStrategy(nil, nil)
 
-	recipe, err := strategy.Fetch(url)
+	recipe, err := strategy.Fetch(context.Background(), url)
 	if err != nil {
 		t.Fatalf("Failed to fetch recipe: %v", err)
 	}
@@ -211,6 +214,199 @@ func TestFirecrawlStrategy_LLMExtractionFallback(t *testing.T) {
 	}
 }
 
+// fidelityJSONLD and fidelityExtraction describe the same recipe as a page's
+// JSON-LD and as the LLM extraction of that page
+const (
+	fidelityJSONLD = `{
+  "@context": "https://schema.org",
+  "@type": "Recipe",
+  "name": "Vegetable Lasagne",
+  "description": "A layered weeknight lasagne.",
+  "image": ["https://example.com/lasagne-1.jpg", "https://example.com/lasagne-2.jpg"],
+  "author": {"@type": "Person", "name": "Jane Baker", "url": "https://example.com/jane"},
+  "prepTime": "PT30M",
+  "cookTime": "PT45M",
+  "totalTime": "PT1H15M",
+  "recipeYield": ["6 servings"],
+  "recipeCategory": ["Main Course"],
+  "recipeCuisine": ["Italian"],
+  "keywords": "lasagne, vegetarian, make ahead",
+  "suitableForDiet": "https://schema.org/VegetarianDiet",
+  "datePublished": "2024-05-01",
+  "dateModified": "2024-06-10",
+  "recipeIngredient": ["For the vegetables:", "2 courgettes", "400g tomatoes", "For the béchamel:", "50g butter", "500ml milk"],
+  "recipeInstructions": [
+    {"@type": "HowToSection", "name": "Make the sauce", "itemListElement": [
+      {"@type": "HowToStep", "text": "Simmer the vegetables."}
+    ]},
+    {"@type": "HowToSection", "name": "Assemble", "itemListElement": [
+      {"@type": "HowToStep", "text": "Layer pasta and sauce."},
+      {"@type": "HowToStep", "text": "Bake for 45 minutes."}
+    ]}
+  ],
+  "nutrition": {
+    "@type": "NutritionInformation",
+    "calories": "520 kcal",
+    "fatContent": "22 g",
+    "saturatedFatContent": "11 g",
+    "cholesterolContent": "45 mg",
+    "sodiumContent": "640 mg",
+    "carbohydrateContent": "58 g",
+    "fiberContent": "6 g",
+    "sugarContent": "12 g",
+    "proteinContent": "21 g"
+  }
+}`
+
+	fidelityExtraction = `{
+  "name": "Vegetable Lasagne",
+  "description": "A layered weeknight lasagne.",
+  "image": ["https://example.com/lasagne-1.jpg", "https://example.com/lasagne-2.jpg"],
+  "author": {"name": "Jane Baker", "url": "https://example.com/jane"},
+  "prepTime": "PT30M",
+  "cookTime": "PT45M",
+  "totalTime": "PT1H15M",
+  "recipeYield": [{"amount": 6, "unit": "servings"}],
+  "recipeCategory": ["Main Course"],
+  "recipeCuisine": ["Italian"],
+  "keywords": ["lasagne", "vegetarian", "make ahead"],
+  "suitableForDiet": ["VegetarianDiet"],
+  "datePublished": "2024-05-01",
+  "dateModified": "2024-06-10",
+  "recipeIngredient": [
+    {"name": "For the vegetables", "ingredients": ["2 courgettes", "400g tomatoes"]},
+    {"name": "For the béchamel", "ingredients": ["50g butter", "500ml milk"]}
+  ],
+  "recipeInstructions": [
+    {"name": "Make the sauce", "steps": ["Simmer the vegetables."]},
+    {"name": "Assemble", "steps": ["Layer pasta and sauce.", "Bake for 45 minutes."]}
+  ],
+  "nutrition": {
+    "calories": "520 kcal",
+    "fatContent": "22 g",
+    "saturatedFatContent": "11 g",
+    "cholesterolContent": "45 mg",
+    "sodiumContent": "640 mg",
+    "carbohydrateContent": "58 g",
+    "fiberContent": "6 g",
+    "sugarContent": "12 g",
+    "proteinContent": "21 g"
+  }
+}`
+)
+
+// TestParseExtractedRecipe_MatchesJSONLD tests that an LLM import of a page
+// carries the same detail as its JSON-LD
+func TestParseExtractedRecipe_MatchesJSONLD(t *testing.T) {
+	html := `<html><head><script type="application/ld+json">` + fidelityJSONLD + `</script></head><body></body></html>`
+	fromJSONLD, err := extractRecipeFromHTML(html, nil)
+	if err != nil || fromJSONLD == nil {
+		t.Fatalf("extractRecipeFromHTML() = %v, %v", fromJSONLD, err)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(fidelityExtraction), &data); err != nil {
+		t.Fatalf("invalid extraction fixture: %v", err)
+	}
+	fromLLM, err := parseExtractedRecipe(data)
+	if err != nil {
+		t.Fatalf("parseExtractedRecipe() error = %v", err)
+	}
+
+	// Only where the recipe came from may differ
+	fromJSONLD.Provenance, fromLLM.Provenance = nil, nil
+	for _, field := range recipeFields {
+		if !field.present(fromJSONLD) {
+			t.Errorf("fixture should set %s", field.key)
+		}
+		if !field.present(fromLLM) {
+			t.Errorf("LLM extraction is missing %s", field.key)
+		}
+	}
+	if !reflect.DeepEqual(fromLLM, fromJSONLD) {
+		got, _ := json.MarshalIndent(fromLLM, "", "  ")
+		want, _ := json.MarshalIndent(fromJSONLD, "", "  ")
+		t.Errorf("LLM recipe differs from JSON-LD recipe\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestParseExtractedRecipe(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		check func(t *testing.T, recipe *Recipe)
+	}{
+		{
+			name: "unnamed section becomes plain steps",
+			data: `{"name": "Toast", "recipeIngredient": [{"ingredients": ["bread"]}], "recipeInstructions": [{"steps": ["Toast the bread.", " "]}]}`,
+			check: func(t *testing.T, recipe *Recipe) {
+				want := []RecipeInstruction{{Type: "HowToStep", Text: "Toast the bread."}}
+				if !reflect.DeepEqual(recipe.RecipeInstructions, want) {
+					t.Errorf("RecipeInstructions = %+v, want %+v", recipe.RecipeInstructions, want)
+				}
+			},
+		},
+		{
+			name: "named ingredient groups keep their headings",
+			data: `{"name": "Cake", "recipeIngredient": [{"name": "For the sponge", "ingredients": ["200g flour", "4 eggs"]}, {"name": "", "ingredients": ["1 pinch salt"]}, {"name": "For the icing:", "ingredients": ["100g sugar"]}, {"name": "Decoration", "ingredients": [" "]}]}`,
+			check: func(t *testing.T, recipe *Recipe) {
+				want := []string{"For the sponge:", "200g flour", "4 eggs", "1 pinch salt", "For the icing:", "100g sugar"}
+				if !reflect.DeepEqual(recipe.RecipeIngredient, want) {
+					t.Errorf("RecipeIngredient = %q, want %q", recipe.RecipeIngredient, want)
+				}
+			},
+		},
+		{
+			name: "single named ingredient group needs no heading",
+			data: `{"name": "Toast", "recipeIngredient": [{"name": "Ingredients", "ingredients": ["bread", "butter"]}]}`,
+			check: func(t *testing.T, recipe *Recipe) {
+				if want := []string{"bread", "butter"}; !reflect.DeepEqual(recipe.RecipeIngredient, want) {
+					t.Errorf("RecipeIngredient = %q, want %q", recipe.RecipeIngredient, want)
+				}
+			},
+		},
+		{
+			name: "yields with and without unit",
+			data: `{"name": "Buns", "recipeYield": [{"amount": 12, "unit": "buns"}, {"amount": 1.5, "unit": ""}, {"unit": "1 tray"}, {}]}`,
+			check: func(t *testing.T, recipe *Recipe) {
+				want := []string{"12 buns", "1.5", "1 tray"}
+				if !reflect.DeepEqual(recipe.RecipeYield, want) {
+					t.Errorf("RecipeYield = %q, want %q", recipe.RecipeYield, want)
+				}
+			},
+		},
+		{
+			name: "unknown diets and empty nutrition are dropped",
+			data: `{"name": "Salad", "suitableForDiet": ["VeganDiet", "Keto"], "nutrition": {"calories": ""}, "author": {"name": ""}}`,
+			check: func(t *testing.T, recipe *Recipe) {
+				if !reflect.DeepEqual(recipe.SuitableForDiet, []string{"VeganDiet"}) {
+					t.Errorf("SuitableForDiet = %q, want VeganDiet", recipe.SuitableForDiet)
+				}
+				if recipe.Nutrition != nil {
+					t.Errorf("Nutrition = %+v, want nil", recipe.Nutrition)
+				}
+				if recipe.Author != nil {
+					t.Errorf("Author = %+v, want nil", recipe.Author)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			recipe, err := parseExtractedRecipe(data)
+			if err != nil {
+				t.Fatalf("parseExtractedRecipe() error = %v", err)
+			}
+			tt.check(t, recipe)
+		})
+	}
+}
+
 // containsGreekCharacters checks if a string contains Greek Unicode characters
 func containsGreekCharacters(s string) bool {
 	for _, r := range s {
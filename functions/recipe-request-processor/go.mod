@@ -8,9 +8,10 @@ require (
 	github.com/PuerkitoBio/goquery v1.10.2
 	github.com/appwrite/sdk-for-go v0.16.0
 	github.com/mendableai/firecrawl-go/v2 v2.4.0
+	golang.org/x/net v0.35.0
 )
 
 require (
 	github.com/andybalholm/cascadia v1.3.3 // indirect
-	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
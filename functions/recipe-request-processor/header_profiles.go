@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Environment variables selecting the header profiles of the HTTP client
+const (
+	// envHeaderProfile names the profile used for every domain without an override
+	envHeaderProfile = "FETCH_HEADER_PROFILE"
+	// envHeaderProfileDomains overrides the profile per domain, as a comma
+	// separated list of domain=profile pairs
+	envHeaderProfileDomains = "FETCH_HEADER_PROFILE_DOMAINS"
+)
+
+// Names of the built-in header profiles
+const (
+	ProfileDesktopChrome  = "desktop-chrome"
+	ProfileDesktopFirefox = "desktop-firefox"
+	ProfileMobileSafari   = "mobile-safari"
+	ProfileMobileChrome   = "mobile-chrome"
+	ProfileBot            = "bot"
+)
+
+// DefaultHeaderProfile is used unless overridden from the environment
+const DefaultHeaderProfile = ProfileDesktopChrome
+
+// HeaderProfile is the set of request headers a client identifies with
+type HeaderProfile struct {
+	Name      string
+	UserAgent string
+	// Headers are sent in addition to the User-Agent. Accept-Encoding is
+	// left to the transport, which then decompresses the response itself.
+	Headers map[string]string
+	// Referer sends the site's root as Referer, like a browser navigating
+	// within the site
+	Referer bool
+}
+
+// browserNavigationHeaders are sent by Chromium-based browsers on a top-level navigation
+var browserNavigationHeaders = map[string]string{
+	"Upgrade-Insecure-Requests": "1",
+	"Sec-Fetch-Dest":            "document",
+	"Sec-Fetch-Mode":            "navigate",
+	"Sec-Fetch-Site":            "none",
+	"Sec-Fetch-User":            "?1",
+}
+
+// headerProfiles are the built-in profiles by name
+var headerProfiles = map[string]HeaderProfile{
+	ProfileDesktopChrome: {
+		Name:      ProfileDesktopChrome,
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		Headers: withHeaders(browserNavigationHeaders, map[string]string{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+			"Accept-Language": "en-US,en;q=0.9",
+			"DNT":             "1",
+			"Connection":      "keep-alive",
+			"Cache-Control":   "max-age=0",
+		}),
+		Referer: true,
+	},
+	ProfileDesktopFirefox: {
+		Name:      ProfileDesktopFirefox,
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		Headers: withHeaders(browserNavigationHeaders, map[string]string{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.5",
+			"DNT":             "1",
+			"Connection":      "keep-alive",
+		}),
+		Referer: true,
+	},
+	ProfileMobileSafari: {
+		Name:      ProfileMobileSafari,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		Headers: map[string]string{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+			"Sec-Fetch-Dest":  "document",
+			"Sec-Fetch-Mode":  "navigate",
+			"Sec-Fetch-Site":  "none",
+		},
+		Referer: true,
+	},
+	ProfileMobileChrome: {
+		Name:      ProfileMobileChrome,
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+		Headers: withHeaders(browserNavigationHeaders, map[string]string{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+		}),
+		Referer: true,
+	},
+	// ProfileBot identifies honestly as our crawler; it is always used when
+	// robots.txt is honored
+	ProfileBot: {
+		Name:      ProfileBot,
+		UserAgent: botUserAgent,
+		Headers: map[string]string{
+			"Accept":          "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+		},
+	},
+}
+
+// withHeaders returns the headers of base merged with extra
+func withHeaders(base, extra map[string]string) map[string]string {
+	headers := make(map[string]string, len(base)+len(extra))
+	for name, value := range base {
+		headers[name] = value
+	}
+	for name, value := range extra {
+		headers[name] = value
+	}
+	return headers
+}
+
+// apply sets the profile's headers on req
+func (p HeaderProfile) apply(req *http.Request) {
+	req.Header.Set("User-Agent", p.UserAgent)
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	if p.Referer && req.URL.Host != "" {
+		req.Header.Set("Referer", req.URL.Scheme+"://"+req.URL.Host)
+	}
+}
+
+// HeaderProfiles selects the header profile of each request by domain
+type HeaderProfiles struct {
+	// Default is the name of the profile used for domains without an override
+	Default string
+	// Domains maps a domain, including its subdomains, to a profile name
+	Domains map[string]string
+}
+
+// HeaderProfilesFromEnv returns the profile selection from the environment.
+// Unknown profile names and malformed pairs are ignored.
+func HeaderProfilesFromEnv() HeaderProfiles {
+	profiles := HeaderProfiles{Default: DefaultHeaderProfile, Domains: map[string]string{}}
+	if name := strings.TrimSpace(os.Getenv(envHeaderProfile)); isHeaderProfile(name) {
+		profiles.Default = name
+	}
+	for _, pair := range strings.Split(os.Getenv(envHeaderProfileDomains), ",") {
+		domain, name, ok := strings.Cut(pair, "=")
+		domain, name = domainKey(strings.TrimSpace(domain)), strings.TrimSpace(name)
+		if ok && domain != "" && isHeaderProfile(name) {
+			profiles.Domains[domain] = name
+		}
+	}
+	return profiles
+}
+
+// isHeaderProfile reports whether name is a built-in profile
+func isHeaderProfile(name string) bool {
+	_, ok := headerProfiles[name]
+	return ok
+}
+
+// forHost returns the profile for host: the override of the most specific
+// matching domain, or the default
+func (p HeaderProfiles) forHost(host string) HeaderProfile {
+	name, matched := p.Default, ""
+	for domain, profile := range p.Domains {
+		if inDomain(host, domain) && len(domain) > len(matched) {
+			name, matched = profile, domain
+		}
+	}
+	if profile, ok := headerProfiles[name]; ok {
+		return profile
+	}
+	return headerProfiles[DefaultHeaderProfile]
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderProfilesFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     string
+		domains     string
+		wantDefault string
+		wantDomains map[string]string
+	}{
+		{
+			name:        "unset",
+			wantDefault: DefaultHeaderProfile,
+			wantDomains: map[string]string{},
+		},
+		{
+			name:        "default and domain overrides",
+			profile:     "desktop-firefox",
+			domains:     "www.Example.com=mobile-safari, recipes.org = bot",
+			wantDefault: ProfileDesktopFirefox,
+			wantDomains: map[string]string{"example.com": ProfileMobileSafari, "recipes.org": ProfileBot},
+		},
+		{
+			name:        "unknown profiles and malformed pairs are ignored",
+			profile:     "netscape",
+			domains:     "example.com=netscape,recipes.org,=bot,food.net=mobile-chrome",
+			wantDefault: DefaultHeaderProfile,
+			wantDomains: map[string]string{"food.net": ProfileMobileChrome},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envHeaderProfile, tt.profile)
+			t.Setenv(envHeaderProfileDomains, tt.domains)
+
+			profiles := HeaderProfilesFromEnv()
+			if profiles.Default != tt.wantDefault {
+				t.Errorf("Default = %q, want %q", profiles.Default, tt.wantDefault)
+			}
+			if len(profiles.Domains) != len(tt.wantDomains) {
+				t.Fatalf("Domains = %v, want %v", profiles.Domains, tt.wantDomains)
+			}
+			for domain, name := range tt.wantDomains {
+				if profiles.Domains[domain] != name {
+					t.Errorf("Domains[%q] = %q, want %q", domain, profiles.Domains[domain], name)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderProfiles_ForHost(t *testing.T) {
+	profiles := HeaderProfiles{
+		Default: ProfileDesktopFirefox,
+		Domains: map[string]string{
+			"example.com":     ProfileMobileSafari,
+			"api.example.com": ProfileBot,
+		},
+	}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", ProfileMobileSafari},
+		{"www.example.com", ProfileMobileSafari},
+		{"blog.example.com", ProfileMobileSafari},
+		{"v2.api.example.com", ProfileBot},
+		{"notexample.com", ProfileDesktopFirefox},
+		{"recipes.org", ProfileDesktopFirefox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := profiles.forHost(tt.host).Name; got != tt.want {
+				t.Errorf("forHost(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPClientStrategy_HeaderProfiles(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testRecipeHTML))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		profiles    HeaderProfiles
+		robots      bool
+		wantProfile string
+		wantReferer bool
+	}{
+		{
+			name:        "default profile",
+			profiles:    HeaderProfiles{Default: DefaultHeaderProfile},
+			wantProfile: ProfileDesktopChrome,
+			wantReferer: true,
+		},
+		{
+			name:        "domain override",
+			profiles:    HeaderProfiles{Default: DefaultHeaderProfile, Domains: map[string]string{"127.0.0.1": ProfileMobileSafari}},
+			wantProfile: ProfileMobileSafari,
+			wantReferer: true,
+		},
+		{
+			name:        "robots.txt mode always identifies as the bot",
+			profiles:    HeaderProfiles{Default: DefaultHeaderProfile, Domains: map[string]string{"127.0.0.1": ProfileMobileSafari}},
+			robots:      true,
+			wantProfile: ProfileBot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := newLocalHTTPClientStrategy()
+			strategy.profiles = tt.profiles
+			if tt.robots {
+				strategy.robots = newRobotsChecker()
+			}
+
+			if _, err := strategy.Fetch(context.Background(), server.URL+"/cake"); err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+
+			profile := headerProfiles[tt.wantProfile]
+			if got := headers.Get("User-Agent"); got != profile.UserAgent {
+				t.Errorf("User-Agent = %q, want the %s profile", got, tt.wantProfile)
+			}
+			for name, value := range profile.Headers {
+				if got := headers.Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+			if got := headers.Get("Referer"); (got == server.URL) != tt.wantReferer {
+				t.Errorf("Referer = %q, want it sent: %v", got, tt.wantReferer)
+			}
+		})
+	}
+}
@@ -1,19 +1,38 @@
 package handler
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"strings"
 	"time"
 )
 
 // HTTPClientStrategy implements FetchStrategy using standard HTTP client
 type HTTPClientStrategy struct {
-	logger *Logger
+	logger      *Logger
+	retry       RetryPolicy
+	maxPageSize int64
+	// transport and validateURL keep requests away from internal addresses;
+	// tests relax them to reach httptest servers
+	transport   http.RoundTripper
+	validateURL func(u *url.URL) error
+	// proxyErr is set when FETCH_PROXY_URL is invalid; the strategy is then
+	// unavailable rather than silently fetching without the proxy
+	proxyErr error
+	// profiles selects the request headers per domain
+	profiles HeaderProfiles
+	// robots is set when robots.txt is honored (opt-in via FETCH_RESPECT_ROBOTS);
+	// requests then identify as our bot instead of a browser
+	robots *robotsChecker
+	// limiter spaces out fetches to the same host across executions
+	limiter *HostRateLimiter
+	// cache keeps fetched pages; fresh pages are reused for cacheTTL and
+	// stale ones are revalidated with a conditional request
+	cache    PageCache
+	cacheTTL time.Duration
 }
 
 // Name returns the strategy name for logging
@@ -21,83 +40,224 @@ func (s *HTTPClientStrategy) Name() string {
 	return "HTTP Client"
 }
 
-// NewHTTPClientStrategy creates a new HTTPClientStrategy with the given logger
-func NewHTTPClientStrategy(logger *Logger) *HTTPClientStrategy {
-	return &HTTPClientStrategy{logger: logger}
+// NewHTTPClientStrategy creates a new HTTPClientStrategy with the given logger,
+// rate limiter and page cache (nil for none) and the retry, robots.txt, cache
+// TTL, header profile and proxy settings from the environment
+func NewHTTPClientStrategy(logger *Logger, limiter *HostRateLimiter, cache PageCache) *HTTPClientStrategy {
+	strategy := &HTTPClientStrategy{
+		logger:      logger,
+		limiter:     limiter,
+		cache:       cache,
+		cacheTTL:    PageCacheTTLFromEnv(),
+		retry:       RetryPolicyFromEnv(),
+		maxPageSize: maxPageSize,
+		transport:   newGuardedTransport(),
+		validateURL: checkURL,
+		profiles:    HeaderProfilesFromEnv(),
+	}
+	if envBool(envRespectRobots) {
+		strategy.robots = sharedRobotsChecker
+	}
+	proxy, err := ProxyConfigFromEnv()
+	if err != nil {
+		strategy.proxyErr = err
+	} else if proxy != nil {
+		strategy.transport = newProxyTransport(proxy, net.DefaultResolver.LookupNetIP)
+	}
+	return strategy
 }
 
-// CanRetry returns true if the error indicates bot protection (403/429) or no JSON-LD found
-func (s *HTTPClientStrategy) CanRetry(err error) bool {
-	if err == nil {
-		return false
+// Fetch fetches HTML from URL and extracts Recipe JSON-LD using HTTP client.
+// The request is bounded by the deadline of ctx.
+func (s *HTTPClientStrategy) Fetch(ctx context.Context, urlStr string) (*Recipe, error) {
+	body, err := s.fetchPage(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logInfo("Parsing HTML for JSON-LD", map[string]interface{}{"body_size": len(body)})
+
+	// Extract recipe from HTML body
+	recipe, err := extractRecipeFromHTML(body, s.logger)
+	if err != nil {
+		return nil, &ParseError{Err: err}
 	}
-	errStr := err.Error()
-	// Retry on bot protection (403/429) or when no JSON-LD is found
-	return strings.Contains(errStr, "403") || strings.Contains(errStr, "429") || errors.Is(err, ErrNoJSONLD)
+
+	// If no recipe found, classify the page and return an error wrapping
+	// ErrNoJSONLD so we can retry with Firecrawl
+	if recipe == nil {
+		class := classifyPage(body, http.StatusOK)
+		s.logInfo("No JSON-LD recipe found in HTML", map[string]interface{}{"page_class": class})
+		return nil, &NoRecipeError{Class: class, HTTPStatus: http.StatusOK}
+	}
+
+	s.logInfo("Recipe extracted successfully from JSON-LD")
+	return recipe, nil
 }
 
-// Fetch fetches HTML from URL and extracts Recipe JSON-LD using HTTP client
-func (s *HTTPClientStrategy) Fetch(urlStr string) (*Recipe, error) {
+// fetchPage returns the UTF-8 HTML of the page, from the page cache when
+// fresh. It honors robots.txt when enabled, waits for the host's rate limit
+// and retries transient failures.
+func (s *HTTPClientStrategy) fetchPage(ctx context.Context, urlStr string) (string, error) {
 	s.logInfo("Starting HTTP fetch")
+	if s.proxyErr != nil {
+		return "", fmt.Errorf("%w: %v", ErrStrategyUnavailable, s.proxyErr)
+	}
 
 	// Create cookie jar to handle sessions and cookies
 	jar, err := cookiejar.New(nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+		return "", fmt.Errorf("failed to create cookie jar: %w", err)
 	}
 
-	// Create HTTP client with timeout and cookie jar to handle sessions
+	// Create HTTP client with cookie jar to handle sessions; the timeout comes from ctx.
+	// The transport and redirect policy keep requests away from internal addresses.
 	client := &http.Client{
-		Timeout: 30 * time.Second,
-		Jar:     jar,
+		Jar:           jar,
+		Transport:     s.transport,
+		CheckRedirect: redirectPolicy(s.validateURL),
+	}
+
+	// Honor robots.txt and Crawl-delay for our bot when enabled
+	var crawlDelay time.Duration
+	page, err := url.Parse(urlStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if s.robots != nil {
+		rules, err := s.robots.check(ctx, client, page)
+		if err != nil {
+			s.logInfo("Page disallowed by robots.txt")
+			return "", err
+		}
+		crawlDelay = rules.crawlDelay
+	}
+
+	// Reuse a fresh cached copy of the page; a stale one is revalidated below.
+	// Pages are cached by the URL requested, as mobile and AMP variants of a
+	// page have their own markup.
+	cacheKey := fetchableURL(page).String()
+	cached := s.cachedPage(ctx, cacheKey)
+	var body string
+	if cached != nil && cached.fresh(s.cacheTTL, time.Now()) {
+		s.logInfo("Using cached page", map[string]interface{}{"fetched_at": cached.FetchedAt})
+		body = cached.Body
+	} else {
+		// Fetch the page, retrying transient failures. Every attempt waits for
+		// the host's rate limit.
+		fetched, err := withRetry(ctx, s.retry, "http_get", s.logInfo, func() (*CachedPage, error) {
+			if s.robots != nil {
+				if err := s.robots.waitTurn(ctx, page.Host, crawlDelay); err != nil {
+					return nil, err
+				}
+			}
+			if err := s.limiter.Wait(ctx, page.Hostname()); err != nil {
+				return nil, err
+			}
+			return s.get(ctx, client, urlStr, cached)
+		})
+		if err != nil {
+			return "", err
+		}
+		s.storePage(ctx, cacheKey, fetched)
+		body = fetched.Body
 	}
+	return body, nil
+}
 
+// get performs a single GET request and returns the page with the UTF-8 body
+// of a 200 HTML response. When a cached page is given, the request is made
+// conditional and a 304 response returns the cached body.
+func (s *HTTPClientStrategy) get(ctx context.Context, client *http.Client, urlStr string, cached *CachedPage) (*CachedPage, error) {
 	// Create request with realistic browser headers to avoid bot detection
-	req, err := http.NewRequest("GET", urlStr, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
 
-	// Set realistic browser headers to mimic a real browser request
-	setBrowserHeaders(req, urlStr)
+	// Set the headers of the domain's profile, unless we honor robots.txt
+	// and must identify as our bot
+	profile := s.profiles.forHost(req.URL.Hostname())
+	if s.robots != nil {
+		profile = headerProfiles[ProfileBot]
+	}
+	profile.apply(req)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
 
-	// Fetch the page
 	resp, err := client.Do(req)
 	if err != nil {
 		s.logError("HTTP request failed", map[string]interface{}{"error": err.Error()})
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
 	s.logInfo("HTTP response received", map[string]interface{}{"status_code": resp.StatusCode})
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	fetched := &CachedPage{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
 	}
 
-	// Read body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	// The cached page is still current
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		s.logInfo("Cached page not modified")
+		fetched.Body = cached.Body
+		if fetched.ETag == "" {
+			fetched.ETag = cached.ETag
+		}
+		if fetched.LastModified == "" {
+			fetched.LastModified = cached.LastModified
+		}
+		return fetched, nil
 	}
 
-	s.logInfo("Parsing HTML for JSON-LD", map[string]interface{}{"body_size": len(body)})
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
 
-	// Extract recipe from HTML body
-	recipe, err := extractRecipeFromHTML(string(body), s.logger)
+	// Read the body only if it is an HTML page within the size limit
+	fetched.Body, err = readHTMLBody(resp, s.maxPageSize)
 	if err != nil {
 		return nil, err
 	}
+	return fetched, nil
+}
 
-	// If no recipe found, return ErrNoJSONLD so we can retry with Firecrawl
-	if recipe == nil {
-		s.logInfo("No JSON-LD recipe found in HTML")
-		return nil, ErrNoJSONLD
+// cachedPage returns the cached copy of the page, or nil if there is none.
+// Cache failures only cost a download, so they are logged and ignored.
+func (s *HTTPClientStrategy) cachedPage(ctx context.Context, key string) *CachedPage {
+	if s.cache == nil {
+		return nil
+	}
+	page, err := s.cache.Get(ctx, key)
+	if err != nil {
+		s.logError("Error reading page cache", map[string]interface{}{"error": err.Error()})
+		return nil
 	}
+	return page
+}
 
-	s.logInfo("Recipe extracted successfully from JSON-LD")
-	return recipe, nil
+// storePage caches a fetched page under key, logging and ignoring failures
+func (s *HTTPClientStrategy) storePage(ctx context.Context, key string, page *CachedPage) {
+	if s.cache == nil {
+		return
+	}
+	page.URL = key
+	if err := s.cache.Put(ctx, key, page); err != nil {
+		s.logError("Error writing page cache", map[string]interface{}{"error": err.Error()})
+	}
 }
 
 // logInfo logs an info message if logger is available
@@ -122,29 +282,8 @@ func (s *HTTPClientStrategy) logError(msg string, fields ...map[string]interface
 	}
 }
 
-// setBrowserHeaders sets realistic browser headers to avoid bot detection
-func setBrowserHeaders(req *http.Request, targetURL string) {
-	// Parse URL to get domain for Referer
-	parsedURL, _ := url.Parse(targetURL)
-	baseURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-
-	// Set realistic browser headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	// Note: Don't set Accept-Encoding manually - Go's http.Transport handles
-	// compression automatically and will decompress the response
-	req.Header.Set("DNT", "1")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("Sec-Fetch-Dest", "document")
-	req.Header.Set("Sec-Fetch-Mode", "navigate")
-	req.Header.Set("Sec-Fetch-Site", "none")
-	req.Header.Set("Sec-Fetch-User", "?1")
-	req.Header.Set("Cache-Control", "max-age=0")
-
-	// Set Referer if we have a base URL
-	if baseURL != "" {
-		req.Header.Set("Referer", baseURL)
-	}
+// setBrowserHeaders sets the headers of the default browser profile to avoid
+// bot detection
+func setBrowserHeaders(req *http.Request) {
+	headerProfiles[DefaultHeaderProfile].apply(req)
 }
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecipeHTML = `<!DOCTYPE html>
+<html>
+<head>
+<script type="application/ld+json">
+{
+  "@type": "Recipe",
+  "name": "Chocolate Cake",
+  "image": "https://example.com/cake.jpg"
+}
+</script>
+</head>
+<body></body>
+</html>`
+
+// newLocalHTTPClientStrategy returns an HTTPClientStrategy that may reach
+// httptest servers on loopback and does not retry
+func newLocalHTTPClientStrategy() *HTTPClientStrategy {
+	strategy := NewHTTPClientStrategy(nil, nil, nil)
+	strategy.transport = http.DefaultTransport
+	strategy.validateURL = func(*url.URL) error { return nil }
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+	return strategy
+}
+
+func TestHTTPClientStrategy_Content(t *testing.T) {
+	const limit = 4096
+
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		wantName        string
+		wantContentType string
+		wantTooLarge    bool
+	}{
+		{
+			name: "HTML page is parsed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte(testRecipeHTML))
+			},
+			wantName: "Chocolate Cake",
+		},
+		{
+			name: "HTML without content type is sniffed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = nil
+				w.Write([]byte(testRecipeHTML))
+			},
+			wantName: "Chocolate Cake",
+		},
+		{
+			name: "HTML served as octet-stream is sniffed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write([]byte(testRecipeHTML))
+			},
+			wantName: "Chocolate Cake",
+		},
+		{
+			name: "PDF is rejected from the header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/pdf")
+				w.Write([]byte("%PDF-1.7"))
+			},
+			wantContentType: "application/pdf",
+		},
+		{
+			name: "PDF served as octet-stream is rejected by sniffing",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write([]byte("%PDF-1.7\n%binary"))
+			},
+			wantContentType: "application/pdf",
+		},
+		{
+			name: "video is rejected without reading the body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "video/mp4")
+				w.Write(make([]byte, 4*limit))
+			},
+			wantContentType: "video/mp4",
+		},
+		{
+			name: "declared length over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Length", strconv.Itoa(2*limit))
+				w.Write([]byte(strings.Repeat("a", 2*limit)))
+			},
+			wantContentType: "text/html",
+			wantTooLarge:    true,
+		},
+		{
+			name: "streamed body over the limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				for i := 0; i < 4; i++ {
+					w.Write([]byte(strings.Repeat("a", limit)))
+					w.(http.Flusher).Flush()
+				}
+			},
+			wantContentType: "text/html",
+			wantTooLarge:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			strategy := newLocalHTTPClientStrategy()
+			strategy.maxPageSize = limit
+
+			recipe, err := strategy.Fetch(context.Background(), server.URL)
+
+			if tt.wantName != "" {
+				if err != nil {
+					t.Fatalf("Fetch() error = %v", err)
+				}
+				if recipe.Name != tt.wantName {
+					t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+				}
+				return
+			}
+
+			var unsupported *UnsupportedContentError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("Fetch() error = %v, want *UnsupportedContentError", err)
+			}
+			if unsupported.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %q, want %q", unsupported.ContentType, tt.wantContentType)
+			}
+			if (unsupported.Limit > 0) != tt.wantTooLarge {
+				t.Errorf("Limit = %d, wantTooLarge %v", unsupported.Limit, tt.wantTooLarge)
+			}
+		})
+	}
+}
+
+func TestHTTPClientStrategy_RetriesTransientStatus(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testRecipeHTML))
+	}))
+	defer server.Close()
+
+	strategy := newLocalHTTPClientStrategy()
+	strategy.retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	recipe, err := strategy.Fetch(context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if recipe.Name != "Chocolate Cake" {
+		t.Errorf("Name = %q, want Chocolate Cake", recipe.Name)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
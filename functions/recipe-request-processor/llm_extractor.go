@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Environment variables configuring the OpenAI-compatible LLM extractor
+const (
+	envLLMAPIURL = "LLM_API_URL"
+	envLLMAPIKey = "LLM_API_KEY"
+	envLLMModel  = "LLM_MODEL"
+)
+
+// maxLLMTextLength caps the page text sent to the LLM, in bytes, so large
+// pages stay within the context window of small local models
+const maxLLMTextLength = 24000
+
+// maxPageImages caps the image URLs listed after the page text
+const maxPageImages = 10
+
+// maxLLMResponseSize caps the chat completion response read from the LLM API
+const maxLLMResponseSize = 1 << 20
+
+// LLMExtractor extracts a recipe from the cleaned text of a page with a
+// language model
+type LLMExtractor interface {
+	// Extract returns the recipe found in text, in the shape described by
+	// buildRecipeExtractionSchema and parsed by parseExtractedRecipe
+	Extract(ctx context.Context, pageURL, text string) (map[string]any, error)
+}
+
+// LLMAPIError reports that the LLM API failed or could not be reached. It
+// wraps ErrStrategyUnavailable so the next strategy is tried.
+type LLMAPIError struct {
+	// StatusCode is the status of the API response, 0 if there was none
+	StatusCode int
+	Err        error
+}
+
+func (e *LLMAPIError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("LLM API error (status %d): %v", e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("LLM API error: %v", e.Err)
+}
+
+func (e *LLMAPIError) Unwrap() []error {
+	return []error{ErrStrategyUnavailable, e.Err}
+}
+
+// OpenAIExtractor implements LLMExtractor with the chat completions API of
+// OpenAI and of compatible servers such as Ollama and llama.cpp. The reply is
+// constrained to the recipe extraction schema.
+type OpenAIExtractor struct {
+	baseURL string
+	apiKey  string
+	model   string
+	// client calls the configured API directly; unlike page fetches it may
+	// reach internal addresses, which is where a local model server runs
+	client *http.Client
+}
+
+// NewOpenAIExtractor creates an OpenAIExtractor for the API at baseURL (e.g.
+// http://localhost:11434/v1). The API key is optional for local servers.
+func NewOpenAIExtractor(baseURL, apiKey, model string) *OpenAIExtractor {
+	return &OpenAIExtractor{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{},
+	}
+}
+
+// LLMExtractorFromEnv returns the extractor configured by LLM_API_URL,
+// LLM_API_KEY and LLM_MODEL, or nil if the URL or model is not set
+func LLMExtractorFromEnv() LLMExtractor {
+	baseURL := strings.TrimSpace(os.Getenv(envLLMAPIURL))
+	model := strings.TrimSpace(os.Getenv(envLLMModel))
+	if baseURL == "" || model == "" {
+		return nil
+	}
+	return NewOpenAIExtractor(baseURL, os.Getenv(envLLMAPIKey), model)
+}
+
+// chatCompletionRequest is the body of a chat completions request
+type chatCompletionRequest struct {
+	Model          string         `json:"model"`
+	Messages       []chatMessage  `json:"messages"`
+	Temperature    float64        `json:"temperature"`
+	ResponseFormat map[string]any `json:"response_format"`
+}
+
+// chatMessage is one message of a chat completion
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatCompletionResponse is the part of a chat completions response we use
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Extract asks the model for the recipe in text. The call is bounded by ctx.
+func (e *OpenAIExtractor) Extract(ctx context.Context, pageURL, text string) (map[string]any, error) {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model: e.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: recipeExtractionPrompt},
+			{Role: "user", Content: "Page URL: " + pageURL + "\n\n" + text},
+		},
+		ResponseFormat: map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "recipe",
+				"schema": buildRecipeExtractionSchema(),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode LLM request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, &LLMAPIError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, transportError(ctx.Err())
+		}
+		return nil, &LLMAPIError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLLMResponseSize))
+	if err != nil {
+		return nil, &LLMAPIError{StatusCode: resp.StatusCode, Err: err}
+	}
+	var completion chatCompletionResponse
+	decodeErr := json.Unmarshal(data, &completion)
+
+	if resp.StatusCode != http.StatusOK {
+		message := truncateMessage(strings.TrimSpace(string(data)))
+		if decodeErr == nil && completion.Error != nil {
+			message = completion.Error.Message
+		}
+		return nil, &LLMAPIError{StatusCode: resp.StatusCode, Err: errors.New(message)}
+	}
+	if decodeErr != nil {
+		return nil, &LLMAPIError{StatusCode: resp.StatusCode, Err: fmt.Errorf("invalid response: %v", decodeErr)}
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("%w: LLM returned no choices", ErrExtractionFailed)
+	}
+
+	var recipe map[string]any
+	if err := json.Unmarshal([]byte(stripCodeFence(completion.Choices[0].Message.Content)), &recipe); err != nil {
+		return nil, fmt.Errorf("%w: LLM reply is not JSON: %v", ErrExtractionFailed, err)
+	}
+	return recipe, nil
+}
+
+// stripCodeFence removes the ```json fence some models put around JSON
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+		content = content[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
+
+// LLMStrategy implements FetchStrategy with an LLMExtractor independent of
+// Firecrawl. It fetches the page like the HTTP client, returns its JSON-LD
+// recipe if there is one, and otherwise sends the cleaned page text to the
+// extractor. Model calls count against the same per-user quota as Firecrawl's
+// LLM extraction.
+type LLMStrategy struct {
+	extractor LLMExtractor
+	pages     *HTTPClientStrategy
+	logger    *Logger
+	retry     RetryPolicy
+	meter     *UsageMeter
+	// allowLLM is false when the pipeline disables LLM extraction
+	allowLLM bool
+}
+
+// NewLLMStrategy creates an LLMStrategy using extractor (nil when none is
+// configured), fetching pages with the given rate limiter and page cache and
+// metering model calls with meter (nil meters nothing)
+func NewLLMStrategy(extractor LLMExtractor, logger *Logger, limiter *HostRateLimiter, cache PageCache, meter *UsageMeter) *LLMStrategy {
+	return &LLMStrategy{
+		extractor: extractor,
+		pages:     NewHTTPClientStrategy(logger, limiter, cache),
+		logger:    logger,
+		retry:     RetryPolicyFromEnv(),
+		meter:     meter,
+		allowLLM:  true,
+	}
+}
+
+// Name returns the strategy name for logging
+func (s *LLMStrategy) Name() string {
+	return "LLM"
+}
+
+// Fetch fetches the page and extracts its recipe, with the LLM if the page
+// has no structured data. All steps share the deadline of ctx.
+func (s *LLMStrategy) Fetch(ctx context.Context, url string) (*Recipe, error) {
+	if !s.allowLLM {
+		return nil, fmt.Errorf("%w: LLM extraction is disabled by the pipeline", ErrStrategyUnavailable)
+	}
+	if s.extractor == nil {
+		return nil, fmt.Errorf("%w: %s and %s environment variables are not set", ErrStrategyUnavailable, envLLMAPIURL, envLLMModel)
+	}
+
+	body, err := s.pages.fetchPage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Structured data is free and more reliable than the model
+	recipe, err := extractRecipeFromHTML(body, s.logger)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	if recipe != nil {
+		s.logInfo("Recipe extracted from JSON-LD, LLM not needed")
+		return recipe, nil
+	}
+
+	// Don't spend a model call on pages that cannot have a recipe
+	noRecipe := &NoRecipeError{Class: classifyPage(body, http.StatusOK), HTTPStatus: http.StatusOK}
+	if noRecipe.isDefinitive() {
+		s.logInfo("Page has no recipe, skipping LLM extraction", map[string]interface{}{"page_class": noRecipe.Class})
+		return nil, noRecipe
+	}
+
+	text, err := pageText(body, url)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	if text == "" {
+		return nil, noRecipe
+	}
+
+	// Each user gets a limited number of LLM extractions; past that the
+	// recipe has to be entered manually
+	if err := s.meter.AllowExtraction(ctx); err != nil {
+		s.logInfo("LLM extraction skipped", map[string]interface{}{"reason": err.Error()})
+		return nil, errors.Join(noRecipe, err)
+	}
+
+	s.logInfo("Attempting LLM extraction", map[string]interface{}{"text_size": len(text)})
+	data, err := withRetry(ctx, s.retry, "llm_extract", s.logInfo, func() (map[string]any, error) {
+		data, err := s.extractor.Extract(ctx, url, text)
+		s.meter.Record(ctx, UsageLLM, err == nil)
+		return data, err
+	})
+	if err != nil {
+		s.logError("LLM extraction failed", map[string]interface{}{"error": err.Error()})
+		return nil, fmt.Errorf("failed to extract recipe with LLM: %w", err)
+	}
+
+	recipe, err = parseExtractedRecipe(data)
+	if err != nil {
+		return nil, err
+	}
+	s.logInfo("Recipe extracted via LLM", map[string]interface{}{"method": "llm"})
+	return recipe, nil
+}
+
+// logInfo logs an info message if logger is available
+func (s *LLMStrategy) logInfo(msg string, fields ...map[string]interface{}) {
+	if s.logger != nil {
+		var f map[string]interface{}
+		if len(fields) > 0 {
+			f = fields[0]
+		}
+		s.logger.Info("llm", msg, f)
+	}
+}
+
+// logError logs an error message if logger is available
+func (s *LLMStrategy) logError(msg string, fields ...map[string]interface{}) {
+	if s.logger != nil {
+		var f map[string]interface{}
+		if len(fields) > 0 {
+			f = fields[0]
+		}
+		s.logger.Error("llm", msg, f)
+	}
+}
+
+// pageTextSkipped are elements whose text is never recipe content
+const pageTextSkipped = "script, style, noscript, template, svg, iframe, nav, header, footer, aside, form, button"
+
+// pageTextBlocks are elements that start a new line of text
+var pageTextBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "table": true, "tr": true,
+	"dl": true, "dt": true, "dd": true, "br": true, "figcaption": true, "blockquote": true,
+}
+
+// pageText returns the readable text of a page for the LLM: the title, the
+// body text with one line per block, list items marked with "- ", and the
+// URLs of the page's images so the model can pick one. The text is capped at
+// maxLLMTextLength.
+func pageText(body, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	images := pageImages(doc, pageURL)
+	doc.Find(pageTextSkipped).Remove()
+
+	var text strings.Builder
+	if title := sanitizeText(doc.Find("title").First().Text()); title != "" {
+		text.WriteString("Title: " + title + "\n\n")
+	}
+	var line strings.Builder
+	flush := func() {
+		if l := sanitizeText(line.String()); l != "" {
+			text.WriteString(l + "\n")
+		}
+		line.Reset()
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			line.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if pageTextBlocks[n.Data] {
+				flush()
+				if n.Data == "li" {
+					line.WriteString("- ")
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == html.ElementNode && pageTextBlocks[n.Data] {
+			flush()
+		}
+	}
+	for _, n := range doc.Find("body").Nodes {
+		walk(n)
+	}
+	flush()
+
+	result := text.String()
+	if len(result) > maxLLMTextLength {
+		// Avoid cutting a multi-byte rune in half
+		cut := maxLLMTextLength
+		for cut > 0 && !utf8.RuneStart(result[cut]) {
+			cut--
+		}
+		result = result[:cut]
+	}
+	if strings.TrimSpace(result) == "" {
+		return "", nil
+	}
+	if len(images) > 0 {
+		result += "\nImages:\n- " + strings.Join(images, "\n- ") + "\n"
+	}
+	return result, nil
+}
+
+// pageImages returns the absolute URLs of the page's OpenGraph and content
+// images, without duplicates, capped at maxPageImages
+func pageImages(doc *goquery.Document, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var images []string
+	seen := map[string]bool{}
+	add := func(src string) {
+		ref, err := url.Parse(strings.TrimSpace(src))
+		if err != nil || src == "" || len(images) >= maxPageImages {
+			return
+		}
+		abs := base.ResolveReference(ref)
+		if abs.Scheme != "http" && abs.Scheme != "https" {
+			return
+		}
+		if s := abs.String(); !seen[s] {
+			seen[s] = true
+			images = append(images, s)
+		}
+	}
+	doc.Find(`meta[property="og:image"]`).Each(func(_ int, meta *goquery.Selection) {
+		add(meta.AttrOr("content", ""))
+	})
+	doc.Find("body img").Each(func(_ int, img *goquery.Selection) {
+		add(img.AttrOr("src", ""))
+	})
+	return images
+}
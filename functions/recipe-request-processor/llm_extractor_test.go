@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// stubChat is an OpenAI-compatible server answering every chat completion
+// with the same reply
+type stubChat struct {
+	*httptest.Server
+	calls atomic.Int32
+	// auth and last are the Authorization header and body of the last request
+	auth string
+	last chatCompletionRequest
+}
+
+// newStubChat starts a stubChat answering with status and reply
+func newStubChat(t *testing.T, status int, reply string) *stubChat {
+	t.Helper()
+	stub := &stubChat{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.calls.Add(1)
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		stub.auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&stub.last); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, reply)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// chatReply wraps content in a chat completions response
+func chatReply(content string) string {
+	reply, _ := json.Marshal(map[string]any{
+		"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": content}}},
+	})
+	return string(reply)
+}
+
+func TestOpenAIExtractor_Extract(t *testing.T) {
+	server := newStubChat(t, http.StatusOK, chatReply("```json\n{\"name\": \"Soup\"}\n```"))
+
+	extractor := NewOpenAIExtractor(server.URL+"/v1/", "secret", "llama3.1")
+	data, err := extractor.Extract(context.Background(), "https://example.com/soup", "Title: Soup")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if data["name"] != "Soup" {
+		t.Errorf("Extract() = %v, want the fenced JSON", data)
+	}
+
+	request := server.last
+	if request.Model != "llama3.1" {
+		t.Errorf("model = %q, want llama3.1", request.Model)
+	}
+	if server.auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the API key", server.auth)
+	}
+	if len(request.Messages) != 2 || !strings.Contains(request.Messages[1].Content, "https://example.com/soup") ||
+		!strings.Contains(request.Messages[1].Content, "Title: Soup") {
+		t.Errorf("messages = %+v, want the prompt then the page URL and text", request.Messages)
+	}
+	schema, _ := request.ResponseFormat["json_schema"].(map[string]any)
+	if request.ResponseFormat["type"] != "json_schema" || schema["schema"] == nil {
+		t.Errorf("response_format = %v, want the recipe extraction schema", request.ResponseFormat)
+	}
+}
+
+func TestOpenAIExtractor_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		reply         string
+		wantAPIError  bool
+		wantTransient bool
+		wantMessage   string
+	}{
+		{
+			name:          "server error",
+			status:        http.StatusServiceUnavailable,
+			reply:         `{"error": {"message": "model is loading"}}`,
+			wantAPIError:  true,
+			wantTransient: true,
+			wantMessage:   "model is loading",
+		},
+		{
+			name:         "unauthorized",
+			status:       http.StatusUnauthorized,
+			reply:        `invalid key`,
+			wantAPIError: true,
+			wantMessage:  "invalid key",
+		},
+		{
+			name:   "reply is not JSON",
+			status: http.StatusOK,
+			reply:  chatReply("Sorry, I can't find a recipe."),
+		},
+		{
+			name:   "no choices",
+			status: http.StatusOK,
+			reply:  `{"choices": []}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubChat(t, tt.status, tt.reply)
+			_, err := NewOpenAIExtractor(server.URL+"/v1", "", "llama3.1").Extract(context.Background(), "https://example.com", "text")
+			if err == nil {
+				t.Fatal("Extract() error = nil")
+			}
+
+			var apiErr *LLMAPIError
+			if errors.As(err, &apiErr) != tt.wantAPIError {
+				t.Fatalf("Extract() error = %v, want LLMAPIError: %v", err, tt.wantAPIError)
+			}
+			if tt.wantAPIError {
+				if apiErr.StatusCode != tt.status || !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Errorf("Extract() error = %v, want status %d with %q", err, tt.status, tt.wantMessage)
+				}
+				if !errors.Is(err, ErrStrategyUnavailable) {
+					t.Error("API errors should make the strategy unavailable")
+				}
+			} else if !errors.Is(err, ErrExtractionFailed) {
+				t.Errorf("Extract() error = %v, want ErrExtractionFailed", err)
+			}
+			if isTransient(err) != tt.wantTransient {
+				t.Errorf("isTransient() = %v, want %v", isTransient(err), tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestPageText(t *testing.T) {
+	page := `<html><head><title>Grandma's Soup | Blog</title>
+<meta property="og:image" content="https://cdn.example.com/soup.jpg">
+<script>var ads = true;</script></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<article>
+  <h1>Grandma's   Soup</h1>
+  <p>Serves <b>4</b>.</p>
+  <ul><li>1 l water</li><li>2 carrots</li></ul>
+  <img src="/img/step.jpg"><img src="data:image/png;base64,AAAA">
+</article>
+<footer>© Blog</footer>
+</body></html>`
+
+	text, err := pageText(page, "https://example.com/soup")
+	if err != nil {
+		t.Fatalf("pageText() error = %v", err)
+	}
+
+	want := "Title: Grandma's Soup | Blog\n\n" +
+		"Grandma's Soup\n" +
+		"Serves 4.\n" +
+		"- 1 l water\n" +
+		"- 2 carrots\n" +
+		"\nImages:\n- https://cdn.example.com/soup.jpg\n- https://example.com/img/step.jpg\n"
+	if text != want {
+		t.Errorf("pageText() =\n%s\nwant:\n%s", text, want)
+	}
+}
+
+func TestPageText_Truncated(t *testing.T) {
+	page := "<html><body><p>" + strings.Repeat("é", maxLLMTextLength) + "</p></body></html>"
+	text, err := pageText(page, "https://example.com")
+	if err != nil {
+		t.Fatalf("pageText() error = %v", err)
+	}
+	if len(text) > maxLLMTextLength || !strings.HasSuffix(text, "é") {
+		t.Errorf("len(pageText()) = %d, want at most %d without a split rune", len(text), maxLLMTextLength)
+	}
+}
+
+// newLocalLLMStrategy returns an LLMStrategy that may reach httptest servers
+// on loopback and does not retry
+func newLocalLLMStrategy(extractor LLMExtractor) *LLMStrategy {
+	strategy := NewLLMStrategy(extractor, nil, nil, nil, nil)
+	strategy.pages = newLocalHTTPClientStrategy()
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+	return strategy
+}
+
+func TestLLMStrategy_Fetch(t *testing.T) {
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/json-ld":
+			fmt.Fprint(w, `<html><head><script type="application/ld+json">{"@type":"Recipe","name":"Structured Soup","image":"https://example.com/soup.jpg","recipeIngredient":["water"]}</script></head></html>`)
+		case "/empty":
+			fmt.Fprint(w, `<html><body></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><h1>Grandma's Soup</h1><ul><li>water</li></ul><p>Boil.</p></body></html>`)
+		}
+	}))
+	defer pages.Close()
+
+	llm := newStubChat(t, http.StatusOK, chatReply(`{"name": "Grandma's Soup", "recipeIngredient": [{"ingredients": ["water"]}], "recipeInstructions": [{"steps": ["Boil."]}]}`))
+	strategy := newLocalLLMStrategy(NewOpenAIExtractor(llm.URL+"/v1", "", "llama3.1"))
+
+	t.Run("extracts the page text with the LLM", func(t *testing.T) {
+		recipe, err := strategy.Fetch(context.Background(), pages.URL+"/soup")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if recipe.Name != "Grandma's Soup" || len(recipe.RecipeInstructions) != 1 {
+			t.Errorf("Fetch() = %+v, want the extracted recipe", recipe)
+		}
+		if recipe.Provenance == nil || recipe.Provenance.Method != MethodLLM {
+			t.Errorf("Provenance = %+v, want method %s", recipe.Provenance, MethodLLM)
+		}
+		if !strings.Contains(llm.last.Messages[1].Content, "- water") {
+			t.Errorf("page text = %q, want the cleaned page", llm.last.Messages[1].Content)
+		}
+	})
+
+	t.Run("JSON-LD needs no LLM", func(t *testing.T) {
+		before := llm.calls.Load()
+		recipe, err := strategy.Fetch(context.Background(), pages.URL+"/json-ld")
+		if err != nil || recipe.Name != "Structured Soup" {
+			t.Fatalf("Fetch() = %v, %v, want the JSON-LD recipe", recipe, err)
+		}
+		if llm.calls.Load() != before {
+			t.Error("LLM called for a page with JSON-LD")
+		}
+	})
+
+	t.Run("page without text", func(t *testing.T) {
+		_, err := strategy.Fetch(context.Background(), pages.URL+"/empty")
+		if !errorAs[*NoRecipeError](err) {
+			t.Errorf("Fetch() error = %v, want NoRecipeError", err)
+		}
+	})
+}
+
+func TestLLMStrategy_Unavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy *LLMStrategy
+	}{
+		{"no extractor configured", newLocalLLMStrategy(nil)},
+		{"disabled by the pipeline", func() *LLMStrategy {
+			strategy := newLocalLLMStrategy(NewOpenAIExtractor("http://127.0.0.1:1/v1", "", "llama3.1"))
+			strategy.allowLLM = false
+			return strategy
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.strategy.Fetch(context.Background(), "https://example.com/soup")
+			if !errors.Is(err, ErrStrategyUnavailable) {
+				t.Errorf("Fetch() error = %v, want ErrStrategyUnavailable", err)
+			}
+		})
+	}
+}
+
+func TestLLMExtractorFromEnv(t *testing.T) {
+	t.Setenv(envLLMAPIURL, "http://localhost:11434/v1")
+	t.Setenv(envLLMModel, "")
+	if extractor := LLMExtractorFromEnv(); extractor != nil {
+		t.Errorf("LLMExtractorFromEnv() = %v without a model, want nil", extractor)
+	}
+
+	t.Setenv(envLLMModel, "llama3.1")
+	extractor, ok := LLMExtractorFromEnv().(*OpenAIExtractor)
+	if !ok || extractor.baseURL != "http://localhost:11434/v1" || extractor.model != "llama3.1" {
+		t.Errorf("LLMExtractorFromEnv() = %+v, want the configured server and model", extractor)
+	}
+}
+
+func TestLLMStrategy_Usage(t *testing.T) {
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><h1>Grandma's Soup</h1><ul><li>water</li></ul><p>Boil.</p></body></html>`)
+	}))
+	defer pages.Close()
+
+	llm := newStubChat(t, http.StatusOK, chatReply(`{"name": "Grandma's Soup", "recipeIngredient": [{"ingredients": ["water"]}], "recipeInstructions": [{"steps": ["Boil."]}]}`))
+	store := newMemoryUsageStore()
+	strategy := newLocalLLMStrategy(NewOpenAIExtractor(llm.URL+"/v1", "", "llama3.1"))
+	strategy.meter = NewUsageMeter(store, LLMQuota{Daily: 1}, nil)
+	ctx := withUsageSubject(context.Background(), "user-1", "request-1")
+
+	// Within quota: the model call is metered without credits
+	if _, err := strategy.Fetch(ctx, pages.URL+"/soup"); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(store.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(store.records))
+	}
+	if record := store.records[0]; record.Operation != UsageLLM || !record.Success || record.Credits != 0 || record.UserID != "user-1" {
+		t.Errorf("record = %+v, want a successful %s call without credits attributed to user-1", record, UsageLLM)
+	}
+
+	// Quota used up: the model is not called
+	_, err := strategy.Fetch(ctx, pages.URL+"/soup")
+	if !errorAs[*QuotaExceededError](err) {
+		t.Fatalf("Fetch() error = %v, want QuotaExceededError", err)
+	}
+	if calls := llm.calls.Load(); calls != 1 {
+		t.Errorf("LLM calls = %d, want 1", calls)
+	}
+	if failure := classifyFetchError(err); failure.Code != CodeQuotaExceeded || failure.PageClass != PageClassNeedsManualEntry {
+		t.Errorf("classifyFetchError() = %+v, want %s with %s", failure, CodeQuotaExceeded, PageClassNeedsManualEntry)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,6 +19,7 @@ type DocumentEventPayload struct {
 	CreatedAt    string `json:"$createdAt"`
 	UpdatedAt    string `json:"$updatedAt"`
 	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url"`
 	Status       string `json:"status"`
 	UserID       string `json:"user_id"`
 }
@@ -58,97 +60,202 @@ func Main(Context openruntimes.Context) openruntimes.Response {
 
 	// Update status to IN_PROGRESS before fetching
 	if err := requestClient.UpdateStatus(payload.ID, StatusInProgress); err != nil {
-		logger.Error("main", "Error updating status to IN_PROGRESS", map[string]interface{}{
-			"error": err.Error(),
-		})
-		return Context.Res.Json(ErrorResponse{
-			Error: "Error updating status to IN_PROGRESS",
-		}, Context.Res.WithStatusCode(http.StatusInternalServerError))
+		return failRequest(Context, logger, requestClient, payload.ID, "Error updating status to IN_PROGRESS",
+			newProcessingError(CodeDatabaseError, err, true))
 	}
 
 	logger.Info("main", "Status updated to IN_PROGRESS")
 
-	// Create strategy executor with HTTP client first, then Firecrawl as fallback
-	// Firecrawl handles bot protection and can use LLM extraction if no JSON-LD is found
-	executor := NewStrategyExecutor(
-		NewHTTPClientStrategy(logger),
-		NewFirecrawlStrategy(logger),
-	)
+	// Fetch the submitted URL without tracking parameters; mobile and AMP
+	// variants are mapped to the regular page only in the canonical URL, since
+	// they may be the only hosts serving the page
+	fetchURL, err := fetchableURLString(payload.URL)
+	if err != nil {
+		return failRequest(Context, logger, requestClient, payload.ID, "URL is not allowed",
+			newProcessingError(CodeInvalidURL, err, false))
+	}
+	// Identify the recipe by the canonical URL recorded by recipe-request;
+	// requests created before it was recorded are canonicalized here
+	canonicalURL := payload.CanonicalURL
+	if canonicalURL == "" {
+		canonicalURL, _ = canonicalURLString(fetchURL)
+	}
 
-	// Fetch recipe using the strategy executor
-	recipe, err := executor.Execute(payload.URL, logger)
+	// Refuse URLs leading to internal addresses before any strategy runs
+	if err := validateFetchURL(fetchURL); err != nil {
+		return failRequest(Context, logger, requestClient, payload.ID, "URL is not allowed", &ProcessingError{
+			Code:    CodeInvalidURL,
+			Message: err.Error(),
+			Err:     err,
+		})
+	}
 
-	if err != nil {
-		logger.Error("main", "Error fetching recipe", map[string]interface{}{
+	// Expand short links, share wrappers and Pinterest pins so the strategies
+	// fetch the recipe page itself. Other resolution failures are left to the
+	// strategies, which follow redirects themselves.
+	resolved, err := NewURLResolver(logger).Resolve(context.Background(), fetchURL)
+	switch {
+	case errorAs[*DisallowedURLError](err):
+		return failRequest(Context, logger, requestClient, payload.ID, "URL is not allowed", &ProcessingError{
+			Code:    CodeInvalidURL,
+			Message: err.Error(),
+			Err:     err,
+		})
+	case err != nil:
+		logger.Warn("main", "Could not resolve link, fetching it as submitted", map[string]interface{}{
 			"error": err.Error(),
 		})
-		// Update status to FAILED
-		if updateErr := requestClient.UpdateStatus(payload.ID, StatusFailed); updateErr != nil {
-			logger.Error("main", "Error updating status to FAILED", map[string]interface{}{
-				"error": updateErr.Error(),
-			})
-			return Context.Res.Json(ErrorResponse{
-				Error: "Error updating status to FAILED",
-			}, Context.Res.WithStatusCode(http.StatusInternalServerError))
-		}
+	case resolved != fetchURL:
+		logger.Info("main", "Resolved link", map[string]interface{}{
+			"resolved_url": resolved,
+		})
+		fetchURL = resolved
+		canonicalURL, _ = canonicalURLString(resolved)
+	}
 
-		return Context.Res.Json(ErrorResponse{
-			Error: "Failed to fetch recipe",
-		}, Context.Res.WithStatusCode(http.StatusInternalServerError))
+	// Both strategies share per-host rate limits stored in Appwrite, so a burst
+	// of imports from one site is spaced out across executions
+	limiter := NewHostRateLimiter(requestClient.RateLimitStore(), RateLimitFromEnv(), logger)
+
+	// Assemble the strategy executor from the pipeline configured for this
+	// environment; by default the HTTP client runs first, then Firecrawl as fallback.
+	// Firecrawl handles bot protection and can use LLM extraction if no JSON-LD is found.
+	// The HTTP client reuses pages cached in Appwrite Storage by earlier imports.
+	pipeline, err := LoadPipelineConfig()
+	if err != nil {
+		return failRequest(Context, logger, requestClient, payload.ID, "Invalid strategy pipeline",
+			newProcessingError(CodeStrategyUnavailable, err, true))
+	}
+	executor, err := DefaultStrategyRegistry().Build(pipeline, StrategyDeps{
+		Logger:    logger,
+		Limiter:   limiter,
+		PageCache: requestClient.PageCache(),
+		// Stop waiting on Firecrawl while it is down or out of credits
+		FirecrawlBreaker: NewCircuitBreaker("firecrawl", requestClient.CircuitStore(), CircuitBreakerConfigFromEnv(), logger),
+		// Meter Firecrawl credits per user and cap their LLM extractions
+		UsageMeter: NewUsageMeter(requestClient.UsageStore(), LLMQuotaFromEnv(), logger),
+		// Model for the llm strategy, e.g. a local Ollama server
+		LLMExtractor: LLMExtractorFromEnv(),
+	})
+	if err != nil {
+		return failRequest(Context, logger, requestClient, payload.ID, "Invalid strategy pipeline",
+			newProcessingError(CodeStrategyUnavailable, err, true))
+	}
+	// Skip or demote strategies that keep failing on this domain, learning
+	// from the outcomes stored in Appwrite
+	executor.WithRouter(NewDomainRouter(requestClient.DomainStatsStore(), RoutingHalfLifeFromEnv(), logger))
+
+	// Fetch recipe using the strategy executor within the fetch budget, leaving
+	// time to save the result before the function timeout. Firecrawl usage is
+	// attributed to the user and request.
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultFetchBudget)
+	defer cancel()
+	ctx = withUsageSubject(ctx, payload.UserID, payload.ID)
+	recipe, err := executor.Execute(ctx, fetchURL, logger)
+
+	// Store fetch attempts for troubleshooting; this must not fail the import
+	if diagErr := requestClient.SaveDiagnostics(payload.ID, executor.Diagnostics()); diagErr != nil {
+		logger.Warn("main", "Error saving diagnostics", map[string]interface{}{
+			"error": diagErr.Error(),
+		})
+	}
+
+	if err != nil {
+		failure := classifyFetchError(err)
+		message := "Failed to fetch recipe"
+		if failure.Code == CodeNoRecipe {
+			message = "No Recipe found on the page"
+		}
+		return failRequest(Context, logger, requestClient, payload.ID, message, failure)
 	}
 
 	if recipe == nil {
-		logger.Error("main", "No recipe structured data found on page")
-		// Update status to FAILED when no recipe found
-		if updateErr := requestClient.UpdateStatus(payload.ID, StatusFailed); updateErr != nil {
-			logger.Error("main", "Error updating status to FAILED", map[string]interface{}{
-				"error": updateErr.Error(),
+		return failRequest(Context, logger, requestClient, payload.ID, "No Recipe structured data found on the page",
+			&ProcessingError{
+				Code:      CodeNoRecipe,
+				Message:   "no recipe returned by any strategy",
+				PageClass: PageClassNoRecipeFound,
 			})
-			return Context.Res.Json(ErrorResponse{
-				Error: "Error updating status to FAILED",
-			}, Context.Res.WithStatusCode(http.StatusInternalServerError))
-		}
-		return Context.Res.Json(ErrorResponse{
-			Error: "No Recipe structured data found on the page",
-		}, Context.Res.WithStatusCode(http.StatusNotFound))
+	}
+
+	// Record the page's own canonical URL when it declares one
+	if recipe.CanonicalURL == "" {
+		recipe.CanonicalURL = canonicalURL
 	}
 
 	// Save recipe to database
 	recipeID, err := requestClient.CreateRecipe(payload.ID, payload.UserID, recipe)
 	if err != nil {
-		logger.Error("main", "Error saving recipe to database", map[string]interface{}{
-			"error": err.Error(),
-		})
-		// Update status to FAILED since we couldn't save
-		if updateErr := requestClient.UpdateStatus(payload.ID, StatusFailed); updateErr != nil {
-			logger.Error("main", "Error updating status to FAILED", map[string]interface{}{
-				"error": updateErr.Error(),
-			})
-		}
-		return Context.Res.Json(ErrorResponse{
-			Error: "Failed to save recipe to database",
-		}, Context.Res.WithStatusCode(http.StatusInternalServerError))
+		return failRequest(Context, logger, requestClient, payload.ID, "Failed to save recipe to database",
+			newProcessingError(CodeSaveFailed, err, true))
 	}
 
 	logger.Info("main", "Recipe saved to database", map[string]interface{}{
 		"recipe_id": recipeID,
 	})
 
-	// Update status to COMPLETED on success
-	if err := requestClient.UpdateStatus(payload.ID, StatusCompleted); err != nil {
-		logger.Error("main", "Error updating status to COMPLETED", map[string]interface{}{
-			"error": err.Error(),
-		})
-		return Context.Res.Json(ErrorResponse{
-			Error: "Error updating status to COMPLETED",
-		}, Context.Res.WithStatusCode(http.StatusInternalServerError))
+	// Score completeness: recipes missing key fields need the user's review
+	report := scoreRecipe(recipe)
+
+	// Update status to COMPLETED or NEEDS_REVIEW
+	if err := requestClient.CompleteRequest(payload.ID, report, recipe.CanonicalURL); err != nil {
+		return failRequest(Context, logger, requestClient, payload.ID, "Error updating status to "+report.Status(),
+			newProcessingError(CodeDatabaseError, err, true))
 	}
 
 	logger.WithDuration("main", "Recipe processing completed", map[string]interface{}{
-		"recipe_id": recipeID,
+		"recipe_id":      recipeID,
+		"status":         report.Status(),
+		"score":          report.Score,
+		"missing_fields": report.MissingFields,
+	})
+
+	response := toRecipeResponse(payload.URL, recipe)
+	response.Completeness = report
+	return Context.Res.Json(response)
+}
+
+// failRequest logs a classified failure, records it on the recipe request row
+// and returns the matching error response
+func failRequest(Context openruntimes.Context, logger *Logger, store RecipeRequestStore, documentID, message string, failure *ProcessingError) openruntimes.Response {
+	logger.Error("main", message, map[string]interface{}{
+		"error":           failure.Message,
+		"error_code":      failure.Code,
+		"retryable":       failure.Retryable,
+		"failed_strategy": failure.Strategy,
+		"http_status":     failure.HTTPStatus,
+		"page_class":      failure.PageClass,
 	})
 
-	return Context.Res.Json(toRecipeResponse(payload.URL, recipe))
+	// Update status to FAILED with the error details
+	if err := store.FailRequest(documentID, failure); err != nil {
+		logger.Error("main", "Error updating status to FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	statusCode := http.StatusInternalServerError
+	switch failure.Code {
+	case CodeNoRecipe:
+		statusCode = http.StatusNotFound
+	case CodeInvalidURL:
+		statusCode = http.StatusBadRequest
+	case CodeBlockedByRobots:
+		statusCode = http.StatusForbidden
+	case CodeRateLimited, CodeQuotaExceeded:
+		statusCode = http.StatusTooManyRequests
+	case CodeStrategyUnavailable:
+		if failure.PageClass == PageClassFallbackUnavailable {
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+	return Context.Res.Json(ErrorResponse{
+		Error:     message,
+		Code:      string(failure.Code),
+		Retryable: failure.Retryable,
+		Reason:    string(failure.PageClass),
+		Hint:      failure.PageClass.Hint(),
+	}, Context.Res.WithStatusCode(statusCode))
 }
 
 func ValidatePayload(payload DocumentEventPayload) error {
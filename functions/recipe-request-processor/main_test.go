@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"testing"
 )
 
@@ -35,13 +36,13 @@ func TestFetchRecipe_Integration(t *testing.T) {
 
 	// Create strategy executor with HTTP client first, then Firecrawl as fallback
 	executor := NewStrategyExecutor(
-		NewHTTPClientStrategy(nil),
-		NewFirecrawlStrategy(nil),
+		NewHTTPClientStrategy(nil, nil, nil),
+		NewFirecrawlStrategy(nil, nil),
 	)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			recipe, err := executor.Execute(tt.urlStr, nil)
+			recipe, err := executor.Execute(context.Background(), tt.urlStr, nil)
 
 			if err != nil {
 				t.Logf("Could not fetch recipe from %s: %v", tt.urlStr, err)
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownATXHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownBoldHeading = regexp.MustCompile(`^(?:\*\*|__)(.+?)(?:\*\*|__)\s*:?\s*$`)
+	markdownSetextRule  = regexp.MustCompile(`^(=+|-+)\s*$`)
+	markdownListItem    = regexp.MustCompile(`^\s*(?:[-*+•]|\d+[.)])\s+(.*)$`)
+	markdownCheckbox    = regexp.MustCompile(`^\[[ xX]\]\s+`)
+	markdownImage       = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLink        = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownEmphasis    = regexp.MustCompile("\\*\\*|__|`")
+
+	// markdownSectionEnd matches sub-headings that end an ingredients or
+	// instructions section rather than grouping its items
+	markdownSectionEnd = regexp.MustCompile(`(?i)^\s*(notes?|tips?|nutrition|equipment|storage|video|faqs?|comments?|related|you may also like)\b`)
+)
+
+// markdownSection is the part of the recipe a run of list items belongs to
+type markdownSection int
+
+const (
+	sectionNone markdownSection = iota
+	sectionIngredients
+	sectionInstructions
+)
+
+// markdownHeadingLevel is used for bold and setext headings, which don't
+// state a level: they group items within # sections and end at any other heading
+const markdownHeadingLevel = 7
+
+// parseMarkdownRecipe builds a recipe from page markdown: the first heading
+// as the name, and the bullet or numbered lists following "Ingredients" and
+// "Instructions" headings (in the languages the HTML heuristics know).
+// Sub-headings inside a section, such as "For the sauce", keep the section
+// going. It returns nil unless both ingredients and steps are found.
+func parseMarkdownRecipe(markdown string) *Recipe {
+	recipe := &Recipe{Context: "https://schema.org", Type: "Recipe"}
+
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	section, sectionLevel := sectionNone, 0
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if text, level, ok := markdownHeading(line, lines, i); ok {
+			if level == markdownHeadingLevel && markdownSetextRule.MatchString(nextLine(lines, i)) {
+				i++ // skip the setext underline
+			}
+			text = markdownText(text)
+			if recipe.Name == "" && section == sectionNone && text != "" &&
+				!ingredientsHeadingRegex.MatchString(text) && !instructionsHeadingRegex.MatchString(text) {
+				recipe.Name = text
+			}
+			switch {
+			case ingredientsHeadingRegex.MatchString(text):
+				section, sectionLevel = sectionIngredients, level
+			case instructionsHeadingRegex.MatchString(text):
+				section, sectionLevel = sectionInstructions, level
+			case section != sectionNone && level > sectionLevel && !markdownSectionEnd.MatchString(text):
+				// A sub-heading within the section
+			default:
+				section = sectionNone
+			}
+			continue
+		}
+
+		match := markdownListItem.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		item := markdownText(markdownCheckbox.ReplaceAllString(match[1], ""))
+		if item == "" {
+			continue
+		}
+		switch section {
+		case sectionIngredients:
+			recipe.RecipeIngredient = append(recipe.RecipeIngredient, item)
+		case sectionInstructions:
+			recipe.RecipeInstructions = append(recipe.RecipeInstructions, RecipeInstruction{Type: "HowToStep", Text: item})
+		}
+	}
+
+	if len(recipe.RecipeIngredient) == 0 || len(recipe.RecipeInstructions) == 0 {
+		return nil
+	}
+	return recipe
+}
+
+// markdownHeading returns the text and level of an ATX (# Title), setext
+// (Title underlined with === or ---) or bold-only (**Title**) heading
+func markdownHeading(line string, lines []string, i int) (string, int, bool) {
+	if match := markdownATXHeading.FindStringSubmatch(line); match != nil {
+		return match[2], len(match[1]), true
+	}
+	if match := markdownBoldHeading.FindStringSubmatch(line); match != nil {
+		return match[1], markdownHeadingLevel, true
+	}
+	if line != "" && !markdownListItem.MatchString(line) && markdownSetextRule.MatchString(nextLine(lines, i)) {
+		return line, markdownHeadingLevel, true
+	}
+	return "", 0, false
+}
+
+// nextLine returns the trimmed line after i, or ""
+func nextLine(lines []string, i int) string {
+	if i+1 >= len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[i+1])
+}
+
+// markdownText strips images, link targets and emphasis markers from inline
+// markdown and sanitizes the result
+func markdownText(s string) string {
+	s = markdownImage.ReplaceAllString(s, "")
+	s = markdownLink.ReplaceAllString(s, "$1")
+	s = markdownEmphasis.ReplaceAllString(s, "")
+	return sanitizeText(strings.TrimSuffix(strings.TrimSpace(s), ":"))
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownRecipe(t *testing.T) {
+	tests := []struct {
+		name            string
+		markdown        string
+		wantName        string
+		wantIngredients []string
+		wantSteps       []string
+	}{
+		{
+			name: "ATX headings",
+			markdown: `# Lemon Cake
+
+A bright, simple cake.
+
+## Ingredients
+
+- 200g flour
+- 2 lemons
+
+## Instructions
+
+1. Zest the lemons.
+2. Bake for 40 minutes.`,
+			wantName:        "Lemon Cake",
+			wantIngredients: []string{"200g flour", "2 lemons"},
+			wantSteps:       []string{"Zest the lemons.", "Bake for 40 minutes."},
+		},
+		{
+			name: "bold and setext headings",
+			markdown: `Tomato Soup
+===========
+
+**Ingredients:**
+
+* 1 kg tomatoes
+* 1 onion
+
+Directions
+----------
+
+1) Chop everything.
+2) Simmer for 30 minutes.`,
+			wantName:        "Tomato Soup",
+			wantIngredients: []string{"1 kg tomatoes", "1 onion"},
+			wantSteps:       []string{"Chop everything.", "Simmer for 30 minutes."},
+		},
+		{
+			name: "sub-headings group items and notes end the section",
+			markdown: `# Tacos
+
+## Ingredients
+
+### For the filling
+
+- 500g beef
+
+### For the salsa
+
+- 3 tomatoes
+
+## Method
+
+1. Brown the beef.
+
+### Notes
+
+- Leftovers keep for 3 days.`,
+			wantName:        "Tacos",
+			wantIngredients: []string{"500g beef", "3 tomatoes"},
+			wantSteps:       []string{"Brown the beef."},
+		},
+		{
+			name: "checkboxes, links and images are stripped",
+			markdown: `# Pancakes
+
+![Pancakes](https://example.com/pancakes.jpg)
+
+## Ingredients
+
+- [ ] 2 **eggs**
+- [x] 250ml [milk](https://example.com/milk)
+
+## Steps
+
+1. Whisk everything.`,
+			wantName:        "Pancakes",
+			wantIngredients: []string{"2 eggs", "250ml milk"},
+			wantSteps:       []string{"Whisk everything."},
+		},
+		{
+			name: "other languages",
+			markdown: `# Apfelkuchen
+
+## Zutaten
+
+- 4 Äpfel
+
+## Zubereitung
+
+1. Äpfel schälen.`,
+			wantName:        "Apfelkuchen",
+			wantIngredients: []string{"4 Äpfel"},
+			wantSteps:       []string{"Äpfel schälen."},
+		},
+		{
+			name: "no title",
+			markdown: `## Ingredients
+
+- 1 egg
+
+## Instructions
+
+1. Boil the egg.`,
+			wantName:        "",
+			wantIngredients: []string{"1 egg"},
+			wantSteps:       []string{"Boil the egg."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := parseMarkdownRecipe(tt.markdown)
+			if recipe == nil {
+				t.Fatal("parseMarkdownRecipe() = nil")
+			}
+			if recipe.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+			}
+			if !reflect.DeepEqual(recipe.RecipeIngredient, tt.wantIngredients) {
+				t.Errorf("RecipeIngredient = %q, want %q", recipe.RecipeIngredient, tt.wantIngredients)
+			}
+			var steps []string
+			for _, step := range recipe.RecipeInstructions {
+				steps = append(steps, step.Text)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("RecipeInstructions = %q, want %q", steps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownRecipe_Incomplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{"empty", ""},
+		{"ingredients only", "# Salad\n\n## Ingredients\n\n- lettuce\n"},
+		{"steps only", "# Salad\n\n## Instructions\n\n1. Toss.\n"},
+		{"lists outside sections", "# Blog\n\n- one\n- two\n\n## Comments\n\n- Looks great!\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recipe := parseMarkdownRecipe(tt.markdown); recipe != nil {
+				t.Errorf("parseMarkdownRecipe() = %+v, want nil", recipe)
+			}
+		})
+	}
+}
+
+func TestFirecrawlStrategy_Markdown(t *testing.T) {
+	var extractCalls int
+	markdown := "## Ingredients\n\n- water\n\n## Instructions\n\n1. Boil."
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "jsonOptions") {
+			extractCalls++
+			fmt.Fprint(w, `{"success":true,"data":{"json":{"name":"Soup","recipeIngredient":[{"ingredients":["water"]}],"recipeInstructions":[{"steps":["Boil"]}]}}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"success":true,"data":{"rawHtml":"<html><body><p>Grandma's soup</p></body></html>","markdown":%q,"metadata":{"statusCode":200,"title":"Grandma's Soup","ogImage":"https://example.com/soup.jpg"}}}`, markdown)
+	}))
+	defer server.Close()
+
+	strategy := NewFirecrawlStrategy(nil, nil)
+	strategy.apiKey = "test-key"
+	strategy.apiURL = server.URL
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+
+	recipe, err := strategy.Fetch(context.Background(), "https://example.com/soup")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if extractCalls != 0 {
+		t.Errorf("extract calls = %d, want markdown to avoid the LLM", extractCalls)
+	}
+	if recipe.Name != "Grandma's Soup" || len(recipe.Image) != 1 || recipe.Image[0] != "https://example.com/soup.jpg" {
+		t.Errorf("Name = %q, Image = %v, want both from the page metadata", recipe.Name, recipe.Image)
+	}
+	if recipe.Provenance == nil || recipe.Provenance.Method != MethodMarkdown {
+		t.Errorf("Provenance = %+v, want method %s", recipe.Provenance, MethodMarkdown)
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// microdataMultiValued lists itemprops that may legitimately repeat; all other
+// properties keep only their first value
+var microdataMultiValued = map[string]bool{
+	"image":              true,
+	"recipeIngredient":   true,
+	"ingredients":        true,
+	"recipeInstructions": true,
+	"recipeCategory":     true,
+	"recipeCuisine":      true,
+	"recipeYield":        true,
+}
+
+// extractMicrodataRecipes extracts every schema.org Recipe described with
+// Microdata (itemscope/itemtype/itemprop attributes)
+func extractMicrodataRecipes(doc *goquery.Document) []*Recipe {
+	var recipes []*Recipe
+	doc.Find("[itemscope][itemtype]").Each(func(_ int, s *goquery.Selection) {
+		itemType, _ := s.Attr("itemtype")
+		if !strings.Contains(itemType, "schema.org/Recipe") {
+			return
+		}
+
+		obj := microdataItem(s)
+
+		// Legacy "ingredients" property predates recipeIngredient
+		if _, ok := obj["recipeIngredient"]; !ok {
+			if legacy, ok := obj["ingredients"]; ok {
+				obj["recipeIngredient"] = legacy
+			}
+		}
+		obj["recipeInstructions"] = microdataInstructions(obj["recipeInstructions"])
+
+		if recipe := parseRecipeObject(obj); recipe != nil {
+			recipes = append(recipes, recipe)
+		}
+	})
+	return recipes
+}
+
+// microdataItem converts an itemscope element into a JSON-LD-like object
+func microdataItem(s *goquery.Selection) map[string]interface{} {
+	obj := map[string]interface{}{}
+	if itemType, ok := s.Attr("itemtype"); ok {
+		// "https://schema.org/Recipe" -> "Recipe"
+		fields := strings.Fields(itemType)
+		if len(fields) > 0 {
+			obj["@type"] = fields[0][strings.LastIndex(fields[0], "/")+1:]
+		}
+	}
+	microdataProperties(s, obj)
+	return obj
+}
+
+// microdataProperties collects itemprops belonging to the current item,
+// without descending into nested items
+func microdataProperties(s *goquery.Selection, obj map[string]interface{}) {
+	s.Children().Each(func(_ int, child *goquery.Selection) {
+		_, scoped := child.Attr("itemscope")
+		if props, ok := child.Attr("itemprop"); ok {
+			value := microdataValue(child)
+			for _, prop := range strings.Fields(props) {
+				addMicrodataProperty(obj, prop, value)
+			}
+		}
+		if !scoped {
+			microdataProperties(child, obj)
+		}
+	})
+}
+
+// addMicrodataProperty stores a property value, accumulating repeated
+// multi-valued properties into an array
+func addMicrodataProperty(obj map[string]interface{}, prop string, value interface{}) {
+	existing, ok := obj[prop]
+	if !ok {
+		if microdataMultiValued[prop] {
+			obj[prop] = []interface{}{value}
+		} else {
+			obj[prop] = value
+		}
+		return
+	}
+	if values, ok := existing.([]interface{}); ok && microdataMultiValued[prop] {
+		obj[prop] = append(values, value)
+	}
+}
+
+// microdataValue returns the value of an itemprop element following the
+// Microdata rules for each element type
+func microdataValue(s *goquery.Selection) interface{} {
+	if _, scoped := s.Attr("itemscope"); scoped {
+		return microdataItem(s)
+	}
+
+	attrs := map[string]string{
+		"meta":   "content",
+		"img":    "src",
+		"audio":  "src",
+		"video":  "src",
+		"source": "src",
+		"embed":  "src",
+		"iframe": "src",
+		"a":      "href",
+		"link":   "href",
+		"area":   "href",
+		"data":   "value",
+		"meter":  "value",
+		"time":   "datetime",
+	}
+	if attr, ok := attrs[goquery.NodeName(s)]; ok {
+		if val, ok := s.Attr(attr); ok && val != "" {
+			return val
+		}
+	}
+	// Many sites put machine-readable values in content on arbitrary elements
+	if val, ok := s.Attr("content"); ok && val != "" {
+		return val
+	}
+	return sanitizeText(s.Text())
+}
+
+// microdataInstructions normalizes instruction values so parseInstructions
+// accepts them: plain text becomes a HowToStep
+func microdataInstructions(val interface{}) interface{} {
+	values, ok := val.([]interface{})
+	if !ok {
+		return val
+	}
+
+	var steps []interface{}
+	for _, item := range values {
+		switch v := item.(type) {
+		case string:
+			if text := sanitizeText(v); text != "" {
+				steps = append(steps, map[string]interface{}{"@type": "HowToStep", "text": text})
+			}
+		case map[string]interface{}:
+			steps = append(steps, v)
+		}
+	}
+	return steps
+}
+
+// extractOpenGraphRecipe builds a partial recipe from OpenGraph meta tags.
+// It never proves the page is a recipe and is only used to complete others.
+func extractOpenGraphRecipe(doc *goquery.Document) *Recipe {
+	recipe := &Recipe{}
+
+	recipe.Name = metaContent(doc, "og:title")
+
+	doc.Find("meta[property='og:image'], meta[property='og:image:url'], meta[property='og:image:secure_url']").Each(func(_ int, s *goquery.Selection) {
+		if content, ok := s.Attr("content"); ok && content != "" {
+			for _, existing := range recipe.Image {
+				if existing == content {
+					return
+				}
+			}
+			recipe.Image = append(recipe.Image, content)
+		}
+	})
+
+	if desc := metaContent(doc, "og:description"); desc != "" {
+		recipe.Description = &desc
+	}
+
+	if recipe.Name == "" && len(recipe.Image) == 0 && recipe.Description == nil {
+		return nil
+	}
+	return recipe
+}
+
+// metaContent returns the sanitized content of the first meta tag with the given property
+func metaContent(doc *goquery.Document, property string) string {
+	content, _ := doc.Find("meta[property='" + property + "']").First().Attr("content")
+	return sanitizeText(content)
+}
+
+var (
+	ingredientsHeadingRegex  = regexp.MustCompile(`(?i)^\s*(ingredients?|zutaten|ingrédients|ingredienti|ingredientes|υλικά)(?:[\s:(]|$)`)
+	instructionsHeadingRegex = regexp.MustCompile(`(?i)^\s*(instructions|directions|method|steps|preparation|zubereitung|préparation|preparazione|preparación|εκτέλεση|οδηγίες)(?:[\s:(]|$)`)
+)
+
+// extractHeuristicRecipe builds a partial recipe from the visible DOM: the
+// page's first <h1> and the lists following "Ingredients"/"Instructions" headings
+func extractHeuristicRecipe(doc *goquery.Document) *Recipe {
+	recipe := &Recipe{}
+
+	recipe.Name = sanitizeText(doc.Find("h1").First().Text())
+
+	headings := doc.Find("h1, h2, h3, h4, h5, h6")
+	headings.EachWithBreak(func(_ int, h *goquery.Selection) bool {
+		if len(recipe.RecipeIngredient) == 0 && ingredientsHeadingRegex.MatchString(h.Text()) {
+			recipe.RecipeIngredient = listItemsAfter(h)
+		}
+		return len(recipe.RecipeIngredient) == 0
+	})
+	headings.EachWithBreak(func(_ int, h *goquery.Selection) bool {
+		if len(recipe.RecipeInstructions) == 0 && instructionsHeadingRegex.MatchString(h.Text()) {
+			for _, text := range listItemsAfter(h) {
+				recipe.RecipeInstructions = append(recipe.RecipeInstructions, RecipeInstruction{
+					Type: "HowToStep",
+					Text: text,
+				})
+			}
+		}
+		return len(recipe.RecipeInstructions) == 0
+	})
+
+	if recipe.Name == "" && len(recipe.RecipeIngredient) == 0 && len(recipe.RecipeInstructions) == 0 {
+		return nil
+	}
+	return recipe
+}
+
+// listItemsAfter returns the items of the first list following a heading,
+// stopping at the next heading. Headings wrapped in a container are handled
+// by retrying from the container.
+func listItemsAfter(heading *goquery.Selection) []string {
+	for _, start := range []*goquery.Selection{heading, heading.Parent()} {
+		var items []string
+		start.NextAll().EachWithBreak(func(_ int, sibling *goquery.Selection) bool {
+			if sibling.Is("h1, h2, h3, h4, h5, h6") {
+				return false
+			}
+			list := sibling
+			if !sibling.Is("ul, ol") {
+				list = sibling.Find("ul, ol").First()
+			}
+			if list.Length() == 0 {
+				return true
+			}
+			list.Find("li").Each(func(_ int, li *goquery.Selection) {
+				if text := sanitizeText(li.Text()); text != "" {
+					items = append(items, text)
+				}
+			})
+			return false
+		})
+		if len(items) > 0 {
+			return items
+		}
+	}
+	return nil
+}
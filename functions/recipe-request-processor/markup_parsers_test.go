@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func mustParseDocument(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func TestExtractMicrodataRecipes(t *testing.T) {
+	html := `<html><body>
+<div itemscope itemtype="https://schema.org/Recipe">
+  <h1 itemprop="name">  Lemon   Cake </h1>
+  <img itemprop="image" src="https://example.com/cake.jpg">
+  <meta itemprop="prepTime" content="PT20M">
+  <time itemprop="cookTime" datetime="PT40M">40 minutes</time>
+  <span itemprop="recipeYield">8</span>
+  <div itemprop="author" itemscope itemtype="https://schema.org/Person">
+    <span itemprop="name">Jane Baker</span>
+  </div>
+  <div itemprop="nutrition" itemscope itemtype="https://schema.org/NutritionInformation">
+    <span itemprop="calories">320 kcal</span>
+  </div>
+  <ul>
+    <li itemprop="recipeIngredient">200g flour</li>
+    <li itemprop="recipeIngredient">2 lemons</li>
+  </ul>
+  <ol>
+    <li itemprop="recipeInstructions">Zest the lemons.</li>
+    <li itemprop="recipeInstructions">Bake for 40 minutes.</li>
+  </ol>
+</div>
+</body></html>`
+
+	recipes := extractMicrodataRecipes(mustParseDocument(t, html))
+	if len(recipes) != 1 {
+		t.Fatalf("got %d recipes, want 1", len(recipes))
+	}
+	recipe := recipes[0]
+
+	if recipe.Name != "Lemon Cake" {
+		t.Errorf("Name = %q, want %q", recipe.Name, "Lemon Cake")
+	}
+	if len(recipe.Image) != 1 || recipe.Image[0] != "https://example.com/cake.jpg" {
+		t.Errorf("Image = %v", recipe.Image)
+	}
+	if recipe.PrepTime == nil || *recipe.PrepTime != "PT20M" {
+		t.Errorf("PrepTime = %v, want PT20M", recipe.PrepTime)
+	}
+	if recipe.CookTime == nil || *recipe.CookTime != "PT40M" {
+		t.Errorf("CookTime = %v, want PT40M", recipe.CookTime)
+	}
+	if len(recipe.RecipeYield) != 1 || recipe.RecipeYield[0] != "8" {
+		t.Errorf("RecipeYield = %v, want [8]", recipe.RecipeYield)
+	}
+	if recipe.Author == nil || recipe.Author.Name != "Jane Baker" {
+		t.Errorf("Author = %+v, want Jane Baker", recipe.Author)
+	}
+	if recipe.Nutrition == nil || recipe.Nutrition.Calories == nil || *recipe.Nutrition.Calories != "320 kcal" {
+		t.Errorf("Nutrition = %+v, want 320 kcal", recipe.Nutrition)
+	}
+	if len(recipe.RecipeIngredient) != 2 {
+		t.Errorf("Ingredients = %v, want 2", recipe.RecipeIngredient)
+	}
+	if len(recipe.RecipeInstructions) != 2 || recipe.RecipeInstructions[1].Text != "Bake for 40 minutes." {
+		t.Errorf("Instructions = %+v", recipe.RecipeInstructions)
+	}
+}
+
+func TestExtractMicrodataRecipes_IgnoresOtherItemTypes(t *testing.T) {
+	html := `<div itemscope itemtype="https://schema.org/Product"><span itemprop="name">Blender</span></div>`
+
+	if recipes := extractMicrodataRecipes(mustParseDocument(t, html)); len(recipes) != 0 {
+		t.Errorf("got %d recipes, want 0", len(recipes))
+	}
+}
+
+func TestExtractOpenGraphRecipe(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		wantNil   bool
+		wantName  string
+		wantImage []string
+		wantDesc  string
+	}{
+		{
+			name: "title, images and description",
+			html: `<head>
+<meta property="og:title" content="Best Pancakes">
+<meta property="og:image" content="https://example.com/a.jpg">
+<meta property="og:image:secure_url" content="https://example.com/a.jpg">
+<meta property="og:image" content="https://example.com/b.jpg">
+<meta property="og:description" content="Fluffy &amp; light">
+</head>`,
+			wantName:  "Best Pancakes",
+			wantImage: []string{"https://example.com/a.jpg", "https://example.com/b.jpg"},
+			wantDesc:  "Fluffy & light",
+		},
+		{
+			name:    "no OpenGraph tags",
+			html:    `<head><title>Nothing</title></head>`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := extractOpenGraphRecipe(mustParseDocument(t, tt.html))
+
+			if tt.wantNil {
+				if recipe != nil {
+					t.Errorf("Expected nil, got %+v", recipe)
+				}
+				return
+			}
+			if recipe == nil {
+				t.Fatal("Expected recipe but got nil")
+			}
+			if recipe.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+			}
+			if strings.Join(recipe.Image, ",") != strings.Join(tt.wantImage, ",") {
+				t.Errorf("Image = %v, want %v", recipe.Image, tt.wantImage)
+			}
+			if recipe.Description == nil || *recipe.Description != tt.wantDesc {
+				t.Errorf("Description = %v, want %q", recipe.Description, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestExtractHeuristicRecipe(t *testing.T) {
+	tests := []struct {
+		name             string
+		html             string
+		wantNil          bool
+		wantName         string
+		wantIngredients  []string
+		wantInstructions []string
+	}{
+		{
+			name: "headings followed by lists",
+			html: `<body>
+<h1>Grandma's Pancakes</h1>
+<h2>Ingredients</h2>
+<ul><li>1 cup flour</li><li>1 egg</li></ul>
+<h2>Instructions</h2>
+<ol><li>Mix everything.</li><li>Fry.</li></ol>
+</body>`,
+			wantName:         "Grandma's Pancakes",
+			wantIngredients:  []string{"1 cup flour", "1 egg"},
+			wantInstructions: []string{"Mix everything.", "Fry."},
+		},
+		{
+			name: "lists wrapped in containers and Greek headings",
+			html: `<body>
+<h1>Μουσακάς</h1>
+<div><h3>Υλικά</h3></div>
+<div class="list"><ul><li>3 μελιτζάνες</li></ul></div>
+<h3>Εκτέλεση</h3>
+<div><ol><li>Ψήνουμε.</li></ol></div>
+</body>`,
+			wantName:         "Μουσακάς",
+			wantIngredients:  []string{"3 μελιτζάνες"},
+			wantInstructions: []string{"Ψήνουμε."},
+		},
+		{
+			name: "list search stops at the next heading",
+			html: `<body>
+<h2>Ingredients</h2>
+<p>See below</p>
+<h2>Comments</h2>
+<ul><li>Great recipe!</li></ul>
+</body>`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := extractHeuristicRecipe(mustParseDocument(t, tt.html))
+
+			if tt.wantNil {
+				if recipe != nil {
+					t.Errorf("Expected nil, got %+v", recipe)
+				}
+				return
+			}
+			if recipe == nil {
+				t.Fatal("Expected recipe but got nil")
+			}
+			if recipe.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+			}
+			if strings.Join(recipe.RecipeIngredient, "|") != strings.Join(tt.wantIngredients, "|") {
+				t.Errorf("Ingredients = %v, want %v", recipe.RecipeIngredient, tt.wantIngredients)
+			}
+			if got := flattenInstructions(recipe.RecipeInstructions); strings.Join(got, "|") != strings.Join(tt.wantInstructions, "|") {
+				t.Errorf("Instructions = %v, want %v", got, tt.wantInstructions)
+			}
+		})
+	}
+}
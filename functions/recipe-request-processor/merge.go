@@ -0,0 +1,145 @@
+package handler
+
+import "strings"
+
+// RecipeSource identifies the markup a recipe field was extracted from
+type RecipeSource string
+
+// Markup sources in precedence order (highest first)
+const (
+	SourceJSONLD    RecipeSource = "json-ld"
+	SourceMicrodata RecipeSource = "microdata"
+	SourceOpenGraph RecipeSource = "opengraph"
+	SourceHeuristic RecipeSource = "heuristic"
+
+	// SourceMarkdown marks fields parsed from the page markdown rendered by Firecrawl
+	SourceMarkdown RecipeSource = "markdown"
+	// SourceLLM marks fields produced by LLM extraction rather than page markup
+	SourceLLM RecipeSource = "llm"
+)
+
+// sourcePrecedence lists markup sources from most to least trusted
+var sourcePrecedence = []RecipeSource{SourceJSONLD, SourceMicrodata, SourceOpenGraph, SourceHeuristic}
+
+// recipeCandidate is a (possibly partial) recipe extracted from one markup source
+type recipeCandidate struct {
+	source RecipeSource
+	recipe *Recipe
+}
+
+// recipeField describes how to detect and copy a single Recipe field during merge
+type recipeField struct {
+	key     string
+	present func(r *Recipe) bool
+	copy    func(dst, src *Recipe)
+}
+
+// recipeFields lists every mergeable Recipe field, keyed by its schema.org name
+var recipeFields = []recipeField{
+	{"name", func(r *Recipe) bool { return r.Name != "" }, func(d, s *Recipe) { d.Name = s.Name }},
+	{"image", func(r *Recipe) bool { return len(r.Image) > 0 }, func(d, s *Recipe) { d.Image = s.Image }},
+	{"author", func(r *Recipe) bool { return r.Author != nil }, func(d, s *Recipe) { d.Author = s.Author }},
+	{"description", func(r *Recipe) bool { return r.Description != nil }, func(d, s *Recipe) { d.Description = s.Description }},
+	{"prepTime", func(r *Recipe) bool { return r.PrepTime != nil }, func(d, s *Recipe) { d.PrepTime = s.PrepTime }},
+	{"cookTime", func(r *Recipe) bool { return r.CookTime != nil }, func(d, s *Recipe) { d.CookTime = s.CookTime }},
+	{"totalTime", func(r *Recipe) bool { return r.TotalTime != nil }, func(d, s *Recipe) { d.TotalTime = s.TotalTime }},
+	{"recipeYield", func(r *Recipe) bool { return len(r.RecipeYield) > 0 }, func(d, s *Recipe) { d.RecipeYield = s.RecipeYield }},
+	{"recipeIngredient", func(r *Recipe) bool { return len(r.RecipeIngredient) > 0 }, func(d, s *Recipe) { d.RecipeIngredient = s.RecipeIngredient }},
+	{"recipeInstructions", func(r *Recipe) bool { return len(r.RecipeInstructions) > 0 }, func(d, s *Recipe) { d.RecipeInstructions = s.RecipeInstructions }},
+	{"recipeCategory", func(r *Recipe) bool { return len(r.RecipeCategory) > 0 }, func(d, s *Recipe) { d.RecipeCategory = s.RecipeCategory }},
+	{"recipeCuisine", func(r *Recipe) bool { return len(r.RecipeCuisine) > 0 }, func(d, s *Recipe) { d.RecipeCuisine = s.RecipeCuisine }},
+	{"nutrition", func(r *Recipe) bool { return r.Nutrition != nil }, func(d, s *Recipe) { d.Nutrition = s.Nutrition }},
+	{"keywords", func(r *Recipe) bool { return r.Keywords != nil }, func(d, s *Recipe) { d.Keywords = s.Keywords }},
+	{"suitableForDiet", func(r *Recipe) bool { return len(r.SuitableForDiet) > 0 }, func(d, s *Recipe) { d.SuitableForDiet = s.SuitableForDiet }},
+	{"datePublished", func(r *Recipe) bool { return r.DatePublished != nil }, func(d, s *Recipe) { d.DatePublished = s.DatePublished }},
+	{"dateModified", func(r *Recipe) bool { return r.DateModified != nil }, func(d, s *Recipe) { d.DateModified = s.DateModified }},
+}
+
+// mergeRecipeCandidates combines candidates field-by-field, taking each field
+// from the highest-precedence source that provides it.
+//
+// A recipe is only produced when the page carries recipe-level evidence: a
+// JSON-LD or Microdata Recipe, or DOM heuristics that found both ingredients
+// and instructions. OpenGraph alone only completes other candidates.
+// The merged recipe must still have a name and an image.
+func mergeRecipeCandidates(candidates []recipeCandidate) *Recipe {
+	ordered := orderCandidates(candidates)
+
+	var primary *recipeCandidate
+	for i := range ordered {
+		if isRecipeEvidence(ordered[i]) {
+			primary = &ordered[i]
+			break
+		}
+	}
+	if primary == nil {
+		return nil
+	}
+
+	merged := &Recipe{
+		Context: primary.recipe.Context,
+		Type:    primary.recipe.Type,
+	}
+	if merged.Context == "" {
+		merged.Context = "https://schema.org"
+	}
+	if merged.Type == "" {
+		merged.Type = "Recipe"
+	}
+
+	fieldSources := map[string]RecipeSource{}
+	for _, candidate := range ordered {
+		if !sameRecipe(primary, &candidate) {
+			continue
+		}
+		for _, field := range recipeFields {
+			if field.present(merged) || !field.present(candidate.recipe) {
+				continue
+			}
+			field.copy(merged, candidate.recipe)
+			fieldSources[field.key] = candidate.source
+		}
+	}
+
+	if merged.Name == "" || len(merged.Image) == 0 {
+		return nil
+	}
+
+	merged.Provenance = newProvenance(fieldSources)
+	return merged
+}
+
+// orderCandidates returns candidates sorted by source precedence, keeping
+// document order for candidates from the same source
+func orderCandidates(candidates []recipeCandidate) []recipeCandidate {
+	ordered := make([]recipeCandidate, 0, len(candidates))
+	for _, source := range sourcePrecedence {
+		for _, candidate := range candidates {
+			if candidate.source == source && candidate.recipe != nil {
+				ordered = append(ordered, candidate)
+			}
+		}
+	}
+	return ordered
+}
+
+// isRecipeEvidence reports whether a candidate proves the page contains a recipe
+func isRecipeEvidence(candidate recipeCandidate) bool {
+	switch candidate.source {
+	case SourceJSONLD, SourceMicrodata:
+		return true
+	case SourceHeuristic:
+		return len(candidate.recipe.RecipeIngredient) > 0 && len(candidate.recipe.RecipeInstructions) > 0
+	}
+	return false
+}
+
+// sameRecipe reports whether candidate may contribute fields to the primary
+// recipe. Structured sources describing a differently named recipe (e.g. a
+// roundup page with several recipes) are skipped; page-level sources always merge.
+func sameRecipe(primary, candidate *recipeCandidate) bool {
+	if candidate.source != SourceJSONLD && candidate.source != SourceMicrodata {
+		return true
+	}
+	return strings.EqualFold(strings.TrimSpace(primary.recipe.Name), strings.TrimSpace(candidate.recipe.Name))
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"testing"
+)
+
+func TestMergeRecipeCandidates(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		candidates  []recipeCandidate
+		wantNil     bool
+		wantName    string
+		wantImage   string
+		wantSources map[string]RecipeSource
+	}{
+		{
+			name:       "no candidates",
+			candidates: nil,
+			wantNil:    true,
+		},
+		{
+			name: "JSON-LD only",
+			candidates: []recipeCandidate{
+				{source: SourceJSONLD, recipe: &Recipe{Name: "Soup", Image: []string{"soup.jpg"}}},
+			},
+			wantName:  "Soup",
+			wantImage: "soup.jpg",
+			wantSources: map[string]RecipeSource{
+				"name":  SourceJSONLD,
+				"image": SourceJSONLD,
+			},
+		},
+		{
+			name: "JSON-LD missing image and nutrition completed from other sources",
+			candidates: []recipeCandidate{
+				{source: SourceOpenGraph, recipe: &Recipe{Name: "Soup | Site", Image: []string{"og.jpg"}, Description: strPtr("OG desc")}},
+				{source: SourceJSONLD, recipe: &Recipe{Name: "Soup", Description: strPtr("LD desc")}},
+				{source: SourceMicrodata, recipe: &Recipe{Name: "Soup", Nutrition: &Nutrition{Calories: strPtr("200 kcal")}}},
+			},
+			wantName:  "Soup",
+			wantImage: "og.jpg",
+			wantSources: map[string]RecipeSource{
+				"name":        SourceJSONLD,
+				"description": SourceJSONLD,
+				"nutrition":   SourceMicrodata,
+				"image":       SourceOpenGraph,
+			},
+		},
+		{
+			name: "OpenGraph alone is not a recipe",
+			candidates: []recipeCandidate{
+				{source: SourceOpenGraph, recipe: &Recipe{Name: "Blog Post", Image: []string{"og.jpg"}}},
+			},
+			wantNil: true,
+		},
+		{
+			name: "heuristics with ingredients and instructions count as a recipe",
+			candidates: []recipeCandidate{
+				{source: SourceOpenGraph, recipe: &Recipe{Image: []string{"og.jpg"}}},
+				{source: SourceHeuristic, recipe: &Recipe{
+					Name:               "Pancakes",
+					RecipeIngredient:   []string{"flour"},
+					RecipeInstructions: []RecipeInstruction{{Type: "HowToStep", Text: "Mix"}},
+				}},
+			},
+			wantName:  "Pancakes",
+			wantImage: "og.jpg",
+			wantSources: map[string]RecipeSource{
+				"name":               SourceHeuristic,
+				"image":              SourceOpenGraph,
+				"recipeIngredient":   SourceHeuristic,
+				"recipeInstructions": SourceHeuristic,
+			},
+		},
+		{
+			name: "heuristics without instructions are not a recipe",
+			candidates: []recipeCandidate{
+				{source: SourceOpenGraph, recipe: &Recipe{Image: []string{"og.jpg"}}},
+				{source: SourceHeuristic, recipe: &Recipe{Name: "Shop", RecipeIngredient: []string{"flour"}}},
+			},
+			wantNil: true,
+		},
+		{
+			name: "differently named structured recipe is not merged",
+			candidates: []recipeCandidate{
+				{source: SourceJSONLD, recipe: &Recipe{Name: "Soup", Image: []string{"soup.jpg"}}},
+				{source: SourceJSONLD, recipe: &Recipe{Name: "Salad", Image: []string{"salad.jpg"}, RecipeIngredient: []string{"lettuce"}}},
+			},
+			wantName:  "Soup",
+			wantImage: "soup.jpg",
+			wantSources: map[string]RecipeSource{
+				"name":  SourceJSONLD,
+				"image": SourceJSONLD,
+			},
+		},
+		{
+			name: "merged recipe without image is rejected",
+			candidates: []recipeCandidate{
+				{source: SourceJSONLD, recipe: &Recipe{Name: "Soup"}},
+			},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := mergeRecipeCandidates(tt.candidates)
+
+			if tt.wantNil {
+				if recipe != nil {
+					t.Errorf("Expected nil, got %+v", recipe)
+				}
+				return
+			}
+
+			if recipe == nil {
+				t.Fatal("Expected recipe but got nil")
+			}
+			if recipe.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", recipe.Name, tt.wantName)
+			}
+			if len(recipe.Image) == 0 || recipe.Image[0] != tt.wantImage {
+				t.Errorf("Image = %v, want first %q", recipe.Image, tt.wantImage)
+			}
+			if recipe.Provenance == nil {
+				t.Fatal("Expected provenance but got nil")
+			}
+			if len(recipe.Provenance.Fields) != len(tt.wantSources) {
+				t.Errorf("Provenance.Fields = %v, want sources %v", recipe.Provenance.Fields, tt.wantSources)
+			}
+			for field, want := range tt.wantSources {
+				if got := recipe.Provenance.Fields[field].Source; got != want {
+					t.Errorf("Provenance.Fields[%q].Source = %q, want %q", field, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/appwrite/sdk-for-go/file"
+	"github.com/appwrite/sdk-for-go/storage"
+)
+
+// PageCacheBucketID is the Storage bucket holding cached pages
+const PageCacheBucketID = "page_cache"
+
+// envPageCacheTTL overrides DefaultPageCacheTTL; 0 revalidates on every fetch
+const envPageCacheTTL = "PAGE_CACHE_TTL_SECONDS"
+
+// DefaultPageCacheTTL is how long a cached page is reused without asking the site
+const DefaultPageCacheTTL = time.Hour
+
+// PageCacheTTLFromEnv returns DefaultPageCacheTTL unless overridden from the environment
+func PageCacheTTLFromEnv() time.Duration {
+	if seconds, ok := envInt(envPageCacheTTL); ok && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return DefaultPageCacheTTL
+}
+
+// CachedPage is a fetched HTML page along with the validators needed to
+// revalidate it with a conditional request
+type CachedPage struct {
+	URL          string    `json:"url"`
+	Body         string    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+// fresh reports whether the page may be reused without revalidation
+func (p *CachedPage) fresh(ttl time.Duration, now time.Time) bool {
+	return now.Sub(p.FetchedAt) < ttl
+}
+
+// PageCache stores fetched pages by requested URL so they are shared across executions
+type PageCache interface {
+	// Get returns the cached page for key, or nil if there is none
+	Get(ctx context.Context, key string) (*CachedPage, error)
+	// Put stores page under key, replacing any previous page
+	Put(ctx context.Context, key string, page *CachedPage) error
+}
+
+// memoryPageCache keeps pages in memory, shared only within one function instance
+type memoryPageCache struct {
+	mu    sync.Mutex
+	pages map[string]CachedPage
+}
+
+// newMemoryPageCache creates an empty memoryPageCache
+func newMemoryPageCache() *memoryPageCache {
+	return &memoryPageCache{pages: map[string]CachedPage{}}
+}
+
+// Get returns a copy of the page cached under key
+func (c *memoryPageCache) Get(_ context.Context, key string) (*CachedPage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, ok := c.pages[key]
+	if !ok {
+		return nil, nil
+	}
+	return &page, nil
+}
+
+// Put stores a copy of page under key
+func (c *memoryPageCache) Put(_ context.Context, key string, page *CachedPage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages[key] = *page
+	return nil
+}
+
+// storagePageCache keeps pages as JSON files in an Appwrite Storage bucket
+type storagePageCache struct {
+	storage  *storage.Storage
+	bucketID string
+}
+
+// PageCache returns a PageCache backed by the page_cache Storage bucket
+func (c *RecipeRequestClient) PageCache() PageCache {
+	return &storagePageCache{storage: c.storage, bucketID: PageCacheBucketID}
+}
+
+// Get downloads and decodes the file cached under key
+func (c *storagePageCache) Get(_ context.Context, key string) (*CachedPage, error) {
+	data, err := c.storage.GetFileDownload(c.bucketID, pageCacheFileID(key))
+	if isAppwriteNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download cached page: %w", err)
+	}
+
+	var page CachedPage
+	if err := json.Unmarshal(*data, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode cached page: %w", err)
+	}
+	// Guard against hash collisions
+	if page.URL != key {
+		return nil, nil
+	}
+	return &page, nil
+}
+
+// Put replaces the file cached under key. Files can't be overwritten, so the
+// previous one is deleted first.
+func (c *storagePageCache) Put(_ context.Context, key string, page *CachedPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached page: %w", err)
+	}
+
+	// The SDK only uploads files from disk
+	tmp, err := os.CreateTemp("", "page-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	fileID := pageCacheFileID(key)
+	if _, err := c.storage.DeleteFile(c.bucketID, fileID); err != nil && !isAppwriteNotFound(err) {
+		return fmt.Errorf("failed to delete previous cached page: %w", err)
+	}
+	if _, err := c.storage.CreateFile(c.bucketID, fileID, file.NewInputFile(tmp.Name(), fileID+".json")); err != nil {
+		return fmt.Errorf("failed to upload cached page: %w", err)
+	}
+	return nil
+}
+
+// pageCacheFileID derives a valid file ID (at most 36 characters) from a cache key
+func pageCacheFileID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
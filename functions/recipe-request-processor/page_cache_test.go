@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCachedPage_Fresh(t *testing.T) {
+	now := time.Now()
+	page := &CachedPage{FetchedAt: now.Add(-30 * time.Minute)}
+
+	if !page.fresh(time.Hour, now) {
+		t.Error("page fetched 30m ago should be fresh with a 1h TTL")
+	}
+	if page.fresh(10*time.Minute, now) {
+		t.Error("page fetched 30m ago should be stale with a 10m TTL")
+	}
+	if page.fresh(0, now) {
+		t.Error("page should never be fresh with a zero TTL")
+	}
+}
+
+func TestHTTPClientStrategy_PageCache(t *testing.T) {
+	const etag = `"v1"`
+
+	tests := []struct {
+		name         string
+		cached       *CachedPage // page in the cache before fetching, nil for none
+		ttl          time.Duration
+		modified     bool // whether the server has a newer page than the cached one
+		wantRequests int
+		wantIfNone   string
+		wantBody     string
+	}{
+		{
+			name:         "uncached page is fetched and stored",
+			ttl:          time.Hour,
+			wantRequests: 1,
+			wantBody:     testRecipeHTML,
+		},
+		{
+			name:     "fresh page is reused without a request",
+			cached:   &CachedPage{Body: testRecipeHTML, ETag: etag, FetchedAt: time.Now()},
+			ttl:      time.Hour,
+			wantBody: testRecipeHTML,
+		},
+		{
+			name:         "stale page is revalidated",
+			cached:       &CachedPage{Body: testRecipeHTML, ETag: etag, FetchedAt: time.Now().Add(-2 * time.Hour)},
+			ttl:          time.Hour,
+			wantRequests: 1,
+			wantIfNone:   etag,
+			wantBody:     testRecipeHTML,
+		},
+		{
+			name:         "modified page replaces the cached one",
+			cached:       &CachedPage{Body: "<html>old</html>", ETag: `"v0"`, FetchedAt: time.Now()},
+			ttl:          0,
+			modified:     true,
+			wantRequests: 1,
+			wantIfNone:   `"v0"`,
+			wantBody:     testRecipeHTML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			var ifNoneMatch string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				ifNoneMatch = r.Header.Get("If-None-Match")
+				if ifNoneMatch == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("ETag", etag)
+				w.Write([]byte(testRecipeHTML))
+			}))
+			defer server.Close()
+
+			cache := newMemoryPageCache()
+			u, _ := url.Parse(server.URL + "/recipe")
+			key := fetchableURL(u).String()
+			if tt.cached != nil {
+				tt.cached.URL = key
+				cache.Put(context.Background(), key, tt.cached)
+			}
+
+			strategy := newLocalHTTPClientStrategy()
+			strategy.cache = cache
+			strategy.cacheTTL = tt.ttl
+
+			recipe, err := strategy.Fetch(context.Background(), u.String())
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if recipe == nil || recipe.Name != "Chocolate Cake" {
+				t.Errorf("Fetch() recipe = %+v, want Chocolate Cake", recipe)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if ifNoneMatch != tt.wantIfNone {
+				t.Errorf("If-None-Match = %q, want %q", ifNoneMatch, tt.wantIfNone)
+			}
+
+			stored, _ := cache.Get(context.Background(), key)
+			if stored == nil {
+				t.Fatal("page was not cached")
+			}
+			if stored.Body != tt.wantBody || stored.ETag != etag {
+				t.Errorf("cached page has %d bytes with ETag %s, want %d bytes with ETag %s", len(stored.Body), stored.ETag, len(tt.wantBody), etag)
+			}
+			if tt.wantRequests > 0 && time.Since(stored.FetchedAt) > time.Minute {
+				t.Errorf("FetchedAt = %v, want refreshed", stored.FetchedAt)
+			}
+		})
+	}
+}
+
+func TestHTTPClientStrategy_PageCacheVariants(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := "Desktop Cake"
+		if r.Host == "m.recipes.test" {
+			name = "Mobile Cake"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"`+r.Host+`"`)
+		fmt.Fprintf(w, `<html><head><script type="application/ld+json">{"@type":"Recipe","name":%q,"image":"https://recipes.test/cake.jpg","recipeIngredient":["flour"],"recipeInstructions":["Bake."]}</script></head></html>`, name)
+	}))
+	defer server.Close()
+
+	// Both hosts are served by the test server
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	cache := newMemoryPageCache()
+	strategy := newLocalHTTPClientStrategy()
+	strategy.transport = transport
+	strategy.cache = cache
+	strategy.cacheTTL = time.Hour
+
+	for _, want := range []struct{ url, name string }{
+		{"http://recipes.test/cake", "Desktop Cake"},
+		{"http://m.recipes.test/cake", "Mobile Cake"},
+	} {
+		recipe, err := strategy.Fetch(context.Background(), want.url)
+		if err != nil {
+			t.Fatalf("Fetch(%q) error = %v", want.url, err)
+		}
+		if recipe == nil || recipe.Name != want.name {
+			t.Errorf("Fetch(%q) recipe = %+v, want %s", want.url, recipe, want.name)
+		}
+		if stored, _ := cache.Get(context.Background(), want.url); stored == nil {
+			t.Errorf("page of %q was not cached under its own URL", want.url)
+		}
+	}
+}
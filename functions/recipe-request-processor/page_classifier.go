@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageClass is a machine-readable reason why a fetched page has no recipe.
+// Values are stored in the failure_reason column and are stable for the app.
+type PageClass string
+
+// Page classes, checked in the order listed
+const (
+	PageClassNotFound      PageClass = "not_found"
+	PageClassParkedDomain  PageClass = "parked_domain"
+	PageClassErrorPage     PageClass = "error_page"
+	PageClassCookieWall    PageClass = "cookie_wall"
+	PageClassPaywall       PageClass = "paywall"
+	PageClassVideoOnly     PageClass = "video_only"
+	PageClassListing       PageClass = "listing"
+	PageClassArticle       PageClass = "article_without_recipe"
+	PageClassNoRecipeFound PageClass = "no_recipe_found"
+	// PageClassBlockedByRobots is not detected from the page: the page was
+	// never fetched because robots.txt disallows it
+	PageClassBlockedByRobots PageClass = "blocked_by_robots"
+	// PageClassFallbackUnavailable is not detected from the page either: the
+	// first strategy failed and the Firecrawl circuit breaker is open
+	PageClassFallbackUnavailable PageClass = "fallback_unavailable"
+	// PageClassNeedsManualEntry means the page needed LLM extraction, but the
+	// user used up their quota for it
+	PageClassNeedsManualEntry PageClass = "needs_manual_entry"
+)
+
+// pageClassHints are user-facing hints explaining each failure and what to try next
+var pageClassHints = map[PageClass]string{
+	PageClassNotFound:            "This page doesn't exist anymore. Check the link or search the site for the recipe.",
+	PageClassParkedDomain:        "This website is no longer active. The recipe may have moved to another site.",
+	PageClassErrorPage:           "The site showed an error page instead of the recipe. Check the link, or add the recipe manually.",
+	PageClassCookieWall:          "The site only showed a cookie consent screen. Open the recipe in your browser and share the link from there.",
+	PageClassPaywall:             "This recipe is behind a paywall or login. Only publicly available recipes can be imported.",
+	PageClassVideoOnly:           "This page only contains a video. Look for a written version of the recipe, often linked in the description.",
+	PageClassListing:             "This page lists several recipes. Open the recipe you want and import that page instead.",
+	PageClassArticle:             "This article doesn't contain a recipe. Try the link to the recipe itself.",
+	PageClassNoRecipeFound:       "We couldn't find a recipe on this page. You can add it manually instead.",
+	PageClassBlockedByRobots:     "This site doesn't allow automated imports of its pages. You can add the recipe manually instead.",
+	PageClassFallbackUnavailable: "Our fallback importer is temporarily unavailable. Please try again in a few minutes.",
+	PageClassNeedsManualEntry:    "You've reached your limit of AI-assisted imports for now. You can add this recipe manually instead.",
+}
+
+// Hint returns the user-facing hint for the page class
+func (c PageClass) Hint() string {
+	return pageClassHints[c]
+}
+
+// NoRecipeError reports that a page was fetched but contains no recipe.
+// It wraps ErrNoJSONLD so strategies can still fall back on it.
+type NoRecipeError struct {
+	Class      PageClass
+	HTTPStatus int
+}
+
+func (e *NoRecipeError) Error() string {
+	return fmt.Sprintf("%s (%s)", ErrNoJSONLD.Error(), e.Class)
+}
+
+func (e *NoRecipeError) Unwrap() error {
+	return ErrNoJSONLD
+}
+
+// isDefinitive reports whether no other strategy could find a recipe on the
+// page. An error page is only recognized from its title, which may be wrong,
+// so other strategies still get to try.
+func (e *NoRecipeError) isDefinitive() bool {
+	return e.Class == PageClassNotFound || e.Class == PageClassParkedDomain
+}
+
+var (
+	parkedDomainPhrases = []string{
+		"domain is for sale", "domain may be for sale", "buy this domain",
+		"domain name is for sale", "this domain is parked", "parked free", "parkingcrew",
+	}
+	paywallPhrases = []string{
+		"subscribe to continue reading", "subscribe to read", "subscribers only",
+		"log in to continue", "sign in to continue", "members only", "create a free account to continue",
+	}
+	videoHosts = []string{"youtube.com", "youtube-nocookie.com", "vimeo.com", "tiktok.com", "instagram.com"}
+)
+
+// errorPageTitle matches the titles of soft 404 pages served with status 200,
+// anchored so recipe titles such as "404-Calorie Lasagna" don't match
+var errorPageTitle = regexp.MustCompile(`^(error\s*)?404(\s|:|$)|^not found(\s*[|:\-–—]|$)|\b404 (error|not found)\b|\bpage (not found|could not be found|doesn't exist|does not exist)\b`)
+
+// minContentLength is the visible text length below which a page is considered
+// to show no real content (e.g. only a consent banner or a video player)
+const minContentLength = 800
+
+// classifyPage explains why a page without a recipe has none.
+// statusCode is the HTTP status of the fetch, or 0 when unknown.
+func classifyPage(htmlContent string, statusCode int) PageClass {
+	if statusCode == http.StatusNotFound || statusCode == http.StatusGone {
+		return PageClassNotFound
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return PageClassNoRecipeFound
+	}
+
+	title := strings.ToLower(sanitizeText(doc.Find("title").First().Text()))
+	ldTypes := jsonLDTypes(doc)
+	ogType := strings.ToLower(metaContent(doc, "og:type"))
+
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	text := strings.ToLower(sanitizeText(body.Text()))
+
+	switch {
+	case containsAny(title+" "+text, parkedDomainPhrases):
+		return PageClassParkedDomain
+	case errorPageTitle.MatchString(title):
+		return PageClassErrorPage
+	case len(text) < minContentLength && strings.Contains(text, "cookie") &&
+		(strings.Contains(text, "accept") || strings.Contains(text, "consent")):
+		return PageClassCookieWall
+	case isPaywalled(doc, text):
+		return PageClassPaywall
+	case isVideoOnly(doc, ogType, ldTypes, text):
+		return PageClassVideoOnly
+	case ldTypes["ItemList"] || ldTypes["CollectionPage"] || doc.Find("article").Length() >= 6:
+		return PageClassListing
+	case ogType == "article" || ldTypes["Article"] || ldTypes["BlogPosting"] || ldTypes["NewsArticle"]:
+		return PageClassArticle
+	}
+	return PageClassNoRecipeFound
+}
+
+// isPaywalled detects paywalls and login walls from schema.org access markup,
+// paywall containers, login forms and subscription prompts
+func isPaywalled(doc *goquery.Document, text string) bool {
+	paywalled := false
+	doc.Find("script[type='application/ld+json']").Each(func(_ int, s *goquery.Selection) {
+		compact := strings.ReplaceAll(strings.ToLower(s.Text()), " ", "")
+		if strings.Contains(compact, `"isaccessibleforfree":false`) || strings.Contains(compact, `"isaccessibleforfree":"false"`) {
+			paywalled = true
+		}
+	})
+	if paywalled {
+		return true
+	}
+	if doc.Find("[class*='paywall'], [id*='paywall']").Length() > 0 {
+		return true
+	}
+	if doc.Find("input[type='password']").Length() > 0 && len(text) < minContentLength {
+		return true
+	}
+	return containsAny(text, paywallPhrases)
+}
+
+// isVideoOnly detects pages whose only content is an embedded or native video
+func isVideoOnly(doc *goquery.Document, ogType string, ldTypes map[string]bool, text string) bool {
+	if strings.HasPrefix(ogType, "video") {
+		return true
+	}
+	if len(text) >= minContentLength {
+		return false
+	}
+	if ldTypes["VideoObject"] || doc.Find("video").Length() > 0 {
+		return true
+	}
+	embedded := false
+	doc.Find("iframe[src]").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		if containsAny(strings.ToLower(src), videoHosts) {
+			embedded = true
+		}
+	})
+	return embedded
+}
+
+// jsonLDTypes returns the set of @type values declared in the page's JSON-LD
+func jsonLDTypes(doc *goquery.Document) map[string]bool {
+	types := map[string]bool{}
+	var collect func(data interface{})
+	collect = func(data interface{}) {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			switch t := v["@type"].(type) {
+			case string:
+				types[t] = true
+			case []interface{}:
+				for _, item := range t {
+					if s, ok := item.(string); ok {
+						types[s] = true
+					}
+				}
+			}
+			collect(v["@graph"])
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		}
+	}
+	doc.Find("script[type='application/ld+json']").Each(func(_ int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err == nil {
+			collect(data)
+		}
+	})
+	return types
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClassifyPage(t *testing.T) {
+	longText := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 20)
+
+	tests := []struct {
+		name       string
+		html       string
+		statusCode int
+		want       PageClass
+	}{
+		{
+			name:       "404 status",
+			html:       `<html><body>Gone</body></html>`,
+			statusCode: 404,
+			want:       PageClassNotFound,
+		},
+		{
+			name: "soft 404 title",
+			html: `<html><head><title>Page Not Found | Food Blog</title></head><body>` + longText + `</body></html>`,
+			want: PageClassErrorPage,
+		},
+		{
+			name: "404 error title",
+			html: `<html><head><title>404 - Food Blog</title></head><body>` + longText + `</body></html>`,
+			want: PageClassErrorPage,
+		},
+		{
+			name: "recipe title starting with 404",
+			html: `<html><head><title>404-Calorie Lasagna</title></head><body>` + longText + `</body></html>`,
+			want: PageClassNoRecipeFound,
+		},
+		{
+			name: "recipe title containing not found",
+			html: `<html><head><title>Not Found Anywhere Else Chili | Food Blog</title></head><body>` + longText + `</body></html>`,
+			want: PageClassNoRecipeFound,
+		},
+		{
+			name: "parked domain",
+			html: `<html><head><title>tastyfood.com</title></head><body>This domain is for sale! Make an offer.</body></html>`,
+			want: PageClassParkedDomain,
+		},
+		{
+			name: "cookie consent wall",
+			html: `<html><body><div id="consent">We use cookies to improve your experience. <button>Accept all</button></div>
+<script>var lots = "of script content that should be ignored";</script></body></html>`,
+			want: PageClassCookieWall,
+		},
+		{
+			name: "paywall via schema.org access markup",
+			html: `<html><head><script type="application/ld+json">{"@type": "NewsArticle", "isAccessibleForFree": false}</script></head><body>` + longText + `</body></html>`,
+			want: PageClassPaywall,
+		},
+		{
+			name: "login wall",
+			html: `<html><body><form><input type="email"><input type="password"><button>Log in</button></form></body></html>`,
+			want: PageClassPaywall,
+		},
+		{
+			name: "video page",
+			html: `<html><head><meta property="og:type" content="video.other"></head><body>` + longText + `</body></html>`,
+			want: PageClassVideoOnly,
+		},
+		{
+			name: "embedded video with little text",
+			html: `<html><body><h1>Pasta</h1><iframe src="https://www.youtube.com/embed/abc"></iframe></body></html>`,
+			want: PageClassVideoOnly,
+		},
+		{
+			name: "recipe index via ItemList",
+			html: `<html><head><script type="application/ld+json">{"@context": "https://schema.org", "@graph": [{"@type": "ItemList"}]}</script></head><body>` + longText + `</body></html>`,
+			want: PageClassListing,
+		},
+		{
+			name: "recipe index via article cards",
+			html: `<html><body>` + strings.Repeat(`<article><a href="/r">Recipe</a></article>`, 8) + longText + `</body></html>`,
+			want: PageClassListing,
+		},
+		{
+			name: "article without recipe",
+			html: `<html><head><meta property="og:type" content="article"></head><body>` + longText + `</body></html>`,
+			want: PageClassArticle,
+		},
+		{
+			name: "unclassified page",
+			html: `<html><body>` + longText + `</body></html>`,
+			want: PageClassNoRecipeFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyPage(tt.html, tt.statusCode); got != tt.want {
+				t.Errorf("classifyPage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageClassHints(t *testing.T) {
+	classes := []PageClass{
+		PageClassNotFound, PageClassParkedDomain, PageClassErrorPage, PageClassCookieWall, PageClassPaywall,
+		PageClassVideoOnly, PageClassListing, PageClassArticle, PageClassNoRecipeFound,
+	}
+	for _, class := range classes {
+		if class.Hint() == "" {
+			t.Errorf("missing hint for %q", class)
+		}
+	}
+}
+
+func TestNoRecipeError(t *testing.T) {
+	err := error(&NoRecipeError{Class: PageClassListing})
+
+	if !errors.Is(err, ErrNoJSONLD) {
+		t.Error("expected NoRecipeError to wrap ErrNoJSONLD")
+	}
+
+	joined := errors.Join(err, errors.New("LLM extraction failed"))
+	var noRecipe *NoRecipeError
+	if !errors.As(joined, &noRecipe) || noRecipe.Class != PageClassListing {
+		t.Errorf("errors.As on joined error = %+v, want class %q", noRecipe, PageClassListing)
+	}
+}
+
+func TestNoRecipeError_IsDefinitive(t *testing.T) {
+	tests := []struct {
+		class PageClass
+		want  bool
+	}{
+		{PageClassNotFound, true},
+		{PageClassParkedDomain, true},
+		{PageClassErrorPage, false},
+		{PageClassNoRecipeFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			if got := (&NoRecipeError{Class: tt.class}).isDefinitive(); got != tt.want {
+				t.Errorf("isDefinitive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-// extractRecipeFromHTML extracts Recipe JSON-LD from HTML content
+// extractRecipeFromHTML extracts a Recipe from HTML content by collecting
+// candidates from every supported markup source and merging them field-by-field
 func extractRecipeFromHTML(htmlContent string, logger *Logger) (*Recipe, error) {
 	// Parse HTML with goquery
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
@@ -16,13 +18,53 @@ func extractRecipeFromHTML(htmlContent string, logger *Logger) (*Recipe, error)
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	// Find all JSON-LD script tags
-	var recipe *Recipe
-	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
-		if recipe != nil {
-			return // Already found a recipe
-		}
+	var candidates []recipeCandidate
+	for _, recipe := range extractJSONLDCandidates(doc, logger) {
+		candidates = append(candidates, recipeCandidate{source: SourceJSONLD, recipe: recipe})
+	}
+	for _, recipe := range extractMicrodataRecipes(doc) {
+		candidates = append(candidates, recipeCandidate{source: SourceMicrodata, recipe: recipe})
+	}
+	if recipe := extractOpenGraphRecipe(doc); recipe != nil {
+		candidates = append(candidates, recipeCandidate{source: SourceOpenGraph, recipe: recipe})
+	}
+	if recipe := extractHeuristicRecipe(doc); recipe != nil {
+		candidates = append(candidates, recipeCandidate{source: SourceHeuristic, recipe: recipe})
+	}
+
+	recipe := mergeRecipeCandidates(candidates)
+	if recipe != nil {
+		recipe.CanonicalURL = pageCanonicalURL(doc)
+	}
+	if recipe != nil && logger != nil {
+		logger.Debug("parser", "Merged recipe from markup sources", map[string]interface{}{
+			"candidates": len(candidates),
+			"method":     recipe.Provenance.Method,
+		})
+	}
+
+	return recipe, nil
+}
+
+// pageCanonicalURL returns the canonical form of the URL the page declares in
+// <link rel=canonical>, or "" if it declares no absolute http(s) URL
+func pageCanonicalURL(doc *goquery.Document) string {
+	href, ok := doc.Find("link[rel~='canonical']").First().Attr("href")
+	if !ok {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return canonicalizeURL(u).String()
+}
 
+// extractJSONLDCandidates returns every Recipe found in the JSON-LD script tags,
+// without requiring an image so that other markup can complete it during merge
+func extractJSONLDCandidates(doc *goquery.Document, logger *Logger) []*Recipe {
+	var recipes []*Recipe
+	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
 		jsonLD := s.Text()
 		if jsonLD == "" {
 			return
@@ -59,36 +101,39 @@ func extractRecipeFromHTML(htmlContent string, logger *Logger) (*Recipe, error)
 		}
 
 		// Handle different JSON-LD formats
-		recipe = extractRecipeFromJSONLD(data)
+		if recipe := walkJSONLD(data, parseRecipeObject); recipe != nil {
+			recipes = append(recipes, recipe)
+		}
 	})
 
-	return recipe, nil
+	return recipes
 }
 
-// extractRecipeFromJSONLD extracts Recipe from various JSON-LD formats
-func extractRecipeFromJSONLD(data interface{}) *Recipe {
+// walkJSONLD walks single objects, @graph containers and arrays, returning the
+// first object accepted by parse
+func walkJSONLD(data interface{}, parse func(map[string]interface{}) *Recipe) *Recipe {
 	switch v := data.(type) {
 	case map[string]interface{}:
 		// Single object - check if it's a Recipe or has @graph
-		if recipe := extractRecipeFromObject(v); recipe != nil {
+		if recipe := parse(v); recipe != nil {
 			return recipe
 		}
 		// Check for @graph array
 		if graph, ok := v["@graph"].([]interface{}); ok {
-			return extractRecipeFromArray(graph)
+			return walkJSONLDArray(graph, parse)
 		}
 	case []interface{}:
 		// Array of objects
-		return extractRecipeFromArray(v)
+		return walkJSONLDArray(v, parse)
 	}
 	return nil
 }
 
-// extractRecipeFromArray extracts Recipe from an array of objects
-func extractRecipeFromArray(arr []interface{}) *Recipe {
+// walkJSONLDArray returns the first object in arr accepted by parse
+func walkJSONLDArray(arr []interface{}, parse func(map[string]interface{}) *Recipe) *Recipe {
 	for _, item := range arr {
 		if obj, ok := item.(map[string]interface{}); ok {
-			if recipe := extractRecipeFromObject(obj); recipe != nil {
+			if recipe := parse(obj); recipe != nil {
 				return recipe
 			}
 		}
@@ -96,8 +141,9 @@ func extractRecipeFromArray(arr []interface{}) *Recipe {
 	return nil
 }
 
-// extractRecipeFromObject extracts Recipe from a single object
-func extractRecipeFromObject(obj map[string]interface{}) *Recipe {
+// parseRecipeObject parses a Recipe-typed object, requiring only a name.
+// Missing fields may be completed later from other markup sources.
+func parseRecipeObject(obj map[string]interface{}) *Recipe {
 	// Check if it's a Recipe type
 	typeVal, ok := obj["@type"].(string)
 	if !ok {
@@ -120,17 +166,8 @@ func extractRecipeFromObject(obj map[string]interface{}) *Recipe {
 		return nil
 	}
 
-	// Required: image (can be string or array)
-	if imageVal, ok := obj["image"]; ok {
-		recipe.Image = parseImage(imageVal)
-		if len(recipe.Image) == 0 {
-			// Image is required, skip if empty
-			return nil
-		}
-	} else {
-		// Image is required, skip if not present
-		return nil
-	}
+	// Image (can be string, array or ImageObject)
+	recipe.Image = parseImage(obj["image"])
 
 	// Optional fields
 	recipe.Context = getString(obj, "@context")
@@ -150,7 +187,7 @@ func extractRecipeFromObject(obj map[string]interface{}) *Recipe {
 	}
 	if yield := parseStringOrArray(obj["recipeYield"]); len(yield) > 0 {
 		recipe.RecipeYield = yield
-	} 
+	}
 	if category := parseStringOrArray(obj["recipeCategory"]); len(category) > 0 {
 		recipe.RecipeCategory = category
 	}
@@ -160,6 +197,9 @@ func extractRecipeFromObject(obj map[string]interface{}) *Recipe {
 	if keywords := getStringPtr(obj, "keywords"); keywords != nil {
 		recipe.Keywords = keywords
 	}
+	if diets := parseDiets(obj["suitableForDiet"]); len(diets) > 0 {
+		recipe.SuitableForDiet = diets
+	}
 	if datePublished := getStringPtr(obj, "datePublished"); datePublished != nil {
 		recipe.DatePublished = datePublished
 	}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -115,6 +116,32 @@ func TestExtractRecipeFromHTML(t *testing.T) {
 </html>`,
 			wantName: "Soup",
 		},
+		{
+			name: "JSON-LD without image completed from OpenGraph",
+			html: `<!DOCTYPE html>
+<html>
+<head>
+<meta property="og:image" content="https://example.com/stew.jpg">
+<script type="application/ld+json">
+{"@type": "Recipe", "name": "Stew"}
+</script>
+</head>
+<body></body>
+</html>`,
+			wantName: "Stew",
+		},
+		{
+			name: "OpenGraph without recipe markup",
+			html: `<!DOCTYPE html>
+<html>
+<head>
+<meta property="og:title" content="About us">
+<meta property="og:image" content="https://example.com/team.jpg">
+</head>
+<body></body>
+</html>`,
+			wantNil: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,7 +175,40 @@ func TestExtractRecipeFromHTML(t *testing.T) {
 	}
 }
 
-func TestExtractRecipeFromJSONLD(t *testing.T) {
+func TestPageCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"no canonical link", `<title>Soup</title>`, ""},
+		{"canonical link is canonicalized", `<link rel="canonical" href="https://Example.com/soup/?utm_source=rss">`, "https://example.com/soup"},
+		{"canonical among other rel values", `<link rel="alternate canonical" href="https://example.com/soup">`, "https://example.com/soup"},
+		{"relative canonical is ignored", `<link rel="canonical" href="/soup">`, ""},
+		{"non-http canonical is ignored", `<link rel="canonical" href="android-app://com.example/soup">`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParseDocument(t, "<html><head>"+tt.head+"</head><body></body></html>")
+			if got := pageCanonicalURL(doc); got != tt.want {
+				t.Errorf("pageCanonicalURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// jsonLDPage returns an HTML page embedding data as its only JSON-LD script
+func jsonLDPage(t *testing.T, data interface{}) string {
+	t.Helper()
+	script, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return `<html><head><script type="application/ld+json">` + string(script) + `</script></head><body></body></html>`
+}
+
+func TestExtractRecipeFromHTML_JSONLDShapes(t *testing.T) {
 	tests := []struct {
 		name     string
 		data     interface{}
@@ -221,7 +281,10 @@ func TestExtractRecipeFromJSONLD(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			recipe := extractRecipeFromJSONLD(tt.data)
+			recipe, err := extractRecipeFromHTML(jsonLDPage(t, tt.data), nil)
+			if err != nil {
+				t.Fatalf("extractRecipeFromHTML() error = %v", err)
+			}
 
 			if tt.wantNil {
 				if recipe != nil {
@@ -241,7 +304,7 @@ func TestExtractRecipeFromJSONLD(t *testing.T) {
 	}
 }
 
-func TestExtractRecipeFromObject(t *testing.T) {
+func TestExtractRecipeFromHTML_JSONLDFields(t *testing.T) {
 	strPtr := func(s string) *string { return &s }
 
 	tests := []struct {
@@ -369,7 +432,10 @@ func TestExtractRecipeFromObject(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			recipe := extractRecipeFromObject(tt.obj)
+			recipe, err := extractRecipeFromHTML(jsonLDPage(t, tt.obj), nil)
+			if err != nil {
+				t.Fatalf("extractRecipeFromHTML() error = %v", err)
+			}
 
 			if tt.wantNil {
 				if recipe != nil {
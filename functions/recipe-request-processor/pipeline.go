@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// envPipeline selects the strategy pipeline: the name of a preset in
+// pipelines/ (e.g. "prod") or an inline JSON PipelineConfig
+const envPipeline = "FETCH_PIPELINE"
+
+// Names under which the built-in strategies are registered
+const (
+	StrategyHTTP      = "http"
+	StrategyFirecrawl = "firecrawl"
+	StrategyLLM       = "llm"
+)
+
+// pipelinePresets are the pipeline configurations shipped with the function,
+// one per environment. They are embedded so they deploy with the binary.
+//
+//go:embed pipelines/*.json
+var pipelinePresets embed.FS
+
+// PipelineConfig defines which strategies run, in which order and how
+type PipelineConfig struct {
+	Strategies []StrategyConfig `json:"strategies"`
+	// HedgeDelayMs enables hedged execution; see StrategyExecutor.WithHedgeDelay
+	HedgeDelayMs int `json:"hedgeDelayMs,omitempty"`
+	// AllowLLMFallback lets strategies fall back to LLM extraction; defaults to true
+	AllowLLMFallback *bool `json:"allowLlmFallback,omitempty"`
+}
+
+// StrategyConfig configures one strategy of the pipeline
+type StrategyConfig struct {
+	// Name is the name the strategy is registered under
+	Name string `json:"name"`
+	// TimeoutMs caps the time the strategy may take; 0 leaves it to the fetch budget
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+}
+
+// DefaultPipelineConfig is used when no pipeline is configured: the HTTP
+// client first, then Firecrawl with LLM fallback, one at a time
+var DefaultPipelineConfig = PipelineConfig{
+	Strategies: []StrategyConfig{{Name: StrategyHTTP}, {Name: StrategyFirecrawl}},
+}
+
+// llmFallbackAllowed reports whether the configuration allows LLM extraction
+func (c PipelineConfig) llmFallbackAllowed() bool {
+	return c.AllowLLMFallback == nil || *c.AllowLLMFallback
+}
+
+// LoadPipelineConfig returns the pipeline selected by FETCH_PIPELINE, or
+// DefaultPipelineConfig when unset. FETCH_HEDGE_DELAY_MS overrides the
+// configured hedge delay.
+func LoadPipelineConfig() (PipelineConfig, error) {
+	config := DefaultPipelineConfig
+	if value := strings.TrimSpace(os.Getenv(envPipeline)); value != "" {
+		var err error
+		if config, err = parsePipelineConfig(value); err != nil {
+			return PipelineConfig{}, fmt.Errorf("invalid %s: %w", envPipeline, err)
+		}
+	}
+	if delay := HedgeDelayFromEnv(); delay > 0 {
+		config.HedgeDelayMs = int(delay.Milliseconds())
+	}
+	return config, nil
+}
+
+// parsePipelineConfig parses inline JSON or loads the named preset
+func parsePipelineConfig(value string) (PipelineConfig, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(value, "{") {
+		preset, err := pipelinePresets.ReadFile("pipelines/" + value + ".json")
+		if err != nil {
+			return PipelineConfig{}, fmt.Errorf("unknown pipeline preset %q", value)
+		}
+		data = preset
+	}
+
+	var config PipelineConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return PipelineConfig{}, err
+	}
+	if len(config.Strategies) == 0 {
+		return PipelineConfig{}, errors.New("pipeline has no strategies")
+	}
+	return config, nil
+}
+
+// StrategyDeps are the shared dependencies handed to strategy factories
+type StrategyDeps struct {
+	Logger           *Logger
+	Limiter          *HostRateLimiter
+	PageCache        PageCache
+	AllowLLMFallback bool
+	// FirecrawlBreaker short-circuits Firecrawl while it keeps failing
+	FirecrawlBreaker *CircuitBreaker
+	// UsageMeter meters Firecrawl and LLM calls and enforces the LLM quota
+	UsageMeter *UsageMeter
+	// LLMExtractor is the model used by the llm strategy, nil if none is configured
+	LLMExtractor LLMExtractor
+}
+
+// StrategyFactory creates a strategy for one execution
+type StrategyFactory func(deps StrategyDeps) FetchStrategy
+
+// StrategyRegistry maps strategy names used in pipeline configurations to
+// the factories creating them
+type StrategyRegistry struct {
+	factories map[string]StrategyFactory
+}
+
+// NewStrategyRegistry creates an empty registry
+func NewStrategyRegistry() *StrategyRegistry {
+	return &StrategyRegistry{factories: map[string]StrategyFactory{}}
+}
+
+// DefaultStrategyRegistry returns a registry with the built-in strategies
+func DefaultStrategyRegistry() *StrategyRegistry {
+	registry := NewStrategyRegistry()
+	registry.Register(StrategyHTTP, func(deps StrategyDeps) FetchStrategy {
+		return NewHTTPClientStrategy(deps.Logger, deps.Limiter, deps.PageCache)
+	})
+	registry.Register(StrategyFirecrawl, func(deps StrategyDeps) FetchStrategy {
+		strategy := NewFirecrawlStrategy(deps.Logger, deps.Limiter)
+		strategy.allowLLM = deps.AllowLLMFallback
+		strategy.breaker = deps.FirecrawlBreaker
+		strategy.meter = deps.UsageMeter
+		return strategy
+	})
+	registry.Register(StrategyLLM, func(deps StrategyDeps) FetchStrategy {
+		strategy := NewLLMStrategy(deps.LLMExtractor, deps.Logger, deps.Limiter, deps.PageCache, deps.UsageMeter)
+		strategy.allowLLM = deps.AllowLLMFallback
+		return strategy
+	})
+	return registry
+}
+
+// Register adds a strategy under name, replacing any previous one
+func (r *StrategyRegistry) Register(name string, factory StrategyFactory) {
+	r.factories[name] = factory
+}
+
+// Build assembles the executor for a pipeline configuration
+func (r *StrategyRegistry) Build(config PipelineConfig, deps StrategyDeps) (*StrategyExecutor, error) {
+	deps.AllowLLMFallback = config.llmFallbackAllowed()
+
+	strategies := make([]FetchStrategy, 0, len(config.Strategies))
+	for _, strategyConfig := range config.Strategies {
+		factory, ok := r.factories[strategyConfig.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown strategy %q (registered: %s)", strategyConfig.Name, strings.Join(r.names(), ", "))
+		}
+		strategy := factory(deps)
+		if strategyConfig.TimeoutMs > 0 {
+			strategy = &timeoutStrategy{FetchStrategy: strategy, timeout: time.Duration(strategyConfig.TimeoutMs) * time.Millisecond}
+		}
+		strategies = append(strategies, strategy)
+	}
+	if len(strategies) == 0 {
+		return nil, errors.New("pipeline has no strategies")
+	}
+
+	hedgeDelay := time.Duration(config.HedgeDelayMs) * time.Millisecond
+	executor := NewStrategyExecutor(strategies...).WithHedgeDelay(hedgeDelay)
+	// Every strategy honors robots.txt once opted in, not only the HTTP client
+	if envBool(envRespectRobots) {
+		executor.WithRobots(sharedRobotsChecker, newRobotsClient())
+	}
+	return executor, nil
+}
+
+// names returns the registered strategy names, sorted
+func (r *StrategyRegistry) names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timeoutStrategy caps the time a strategy may take
+type timeoutStrategy struct {
+	FetchStrategy
+	timeout time.Duration
+}
+
+// Fetch runs the wrapped strategy with the timeout applied to ctx
+func (s *timeoutStrategy) Fetch(ctx context.Context, url string) (*Recipe, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.FetchStrategy.Fetch(ctx, url)
+}
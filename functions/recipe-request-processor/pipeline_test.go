@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePipelineConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		wantStrategies []string
+		wantLLM        bool
+		wantErr        bool
+	}{
+		{
+			name:           "inline JSON",
+			value:          `{"strategies":[{"name":"firecrawl"},{"name":"http","timeoutMs":5000}],"hedgeDelayMs":2000}`,
+			wantStrategies: []string{StrategyFirecrawl, StrategyHTTP},
+			wantLLM:        true,
+		},
+		{
+			name:           "preset",
+			value:          "dev",
+			wantStrategies: []string{StrategyHTTP, StrategyFirecrawl},
+			wantLLM:        false,
+		},
+		{name: "unknown preset", value: "qa", wantErr: true},
+		{name: "malformed JSON", value: `{"strategies":`, wantErr: true},
+		{name: "no strategies", value: `{"strategies":[]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parsePipelineConfig(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePipelineConfig() = %+v, want error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePipelineConfig() error = %v", err)
+			}
+			var names []string
+			for _, strategy := range config.Strategies {
+				names = append(names, strategy.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantStrategies, ",") {
+				t.Errorf("strategies = %v, want %v", names, tt.wantStrategies)
+			}
+			if config.llmFallbackAllowed() != tt.wantLLM {
+				t.Errorf("llmFallbackAllowed() = %v, want %v", config.llmFallbackAllowed(), tt.wantLLM)
+			}
+		})
+	}
+}
+
+func TestLoadPipelineConfig(t *testing.T) {
+	t.Run("default pipeline", func(t *testing.T) {
+		t.Setenv(envPipeline, "")
+		t.Setenv(envHedgeDelay, "")
+		config, err := LoadPipelineConfig()
+		if err != nil {
+			t.Fatalf("LoadPipelineConfig() error = %v", err)
+		}
+		if len(config.Strategies) != 2 || config.HedgeDelayMs != 0 || !config.llmFallbackAllowed() {
+			t.Errorf("LoadPipelineConfig() = %+v, want the default pipeline", config)
+		}
+	})
+
+	t.Run("hedge delay from environment overrides the preset", func(t *testing.T) {
+		t.Setenv(envPipeline, "prod")
+		t.Setenv(envHedgeDelay, "1500")
+		config, err := LoadPipelineConfig()
+		if err != nil {
+			t.Fatalf("LoadPipelineConfig() error = %v", err)
+		}
+		if config.HedgeDelayMs != 1500 {
+			t.Errorf("HedgeDelayMs = %d, want 1500", config.HedgeDelayMs)
+		}
+	})
+
+	t.Run("invalid pipeline", func(t *testing.T) {
+		t.Setenv(envPipeline, "missing")
+		if _, err := LoadPipelineConfig(); err == nil {
+			t.Error("LoadPipelineConfig() error = nil, want error")
+		}
+	})
+}
+
+func TestPipelinePresets_Build(t *testing.T) {
+	presets, err := fs.Glob(pipelinePresets, "pipelines/*.json")
+	if err != nil || len(presets) == 0 {
+		t.Fatalf("no pipeline presets found: %v", err)
+	}
+	for _, preset := range presets {
+		name := strings.TrimSuffix(strings.TrimPrefix(preset, "pipelines/"), ".json")
+		t.Run(name, func(t *testing.T) {
+			config, err := parsePipelineConfig(name)
+			if err != nil {
+				t.Fatalf("parsePipelineConfig() error = %v", err)
+			}
+			if _, err := DefaultStrategyRegistry().Build(config, StrategyDeps{}); err != nil {
+				t.Errorf("Build() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStrategyRegistry_Build(t *testing.T) {
+	slow := &fakeStrategy{name: "slow", block: true}
+	fast := &fakeStrategy{name: "fast", recipe: &Recipe{Name: "Soup"}}
+	registry := NewStrategyRegistry()
+	registry.Register("slow", func(StrategyDeps) FetchStrategy { return slow })
+	registry.Register("fast", func(StrategyDeps) FetchStrategy { return fast })
+
+	t.Run("custom pipeline with timeout", func(t *testing.T) {
+		executor, err := registry.Build(PipelineConfig{
+			Strategies: []StrategyConfig{{Name: "slow", TimeoutMs: 20}, {Name: "fast"}},
+		}, StrategyDeps{})
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+
+		start := time.Now()
+		recipe, err := executor.Execute(context.Background(), "https://example.com/recipe", nil)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if recipe.Provenance.Strategy != "fast" {
+			t.Errorf("Strategy = %q, want fast", recipe.Provenance.Strategy)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Execute took %v, want the slow strategy timed out after 20ms", elapsed)
+		}
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		_, err := registry.Build(PipelineConfig{Strategies: []StrategyConfig{{Name: "browser"}}}, StrategyDeps{})
+		if err == nil || !strings.Contains(err.Error(), "fast, slow") {
+			t.Errorf("Build() error = %v, want unknown strategy listing registered names", err)
+		}
+	})
+
+	t.Run("empty pipeline", func(t *testing.T) {
+		if _, err := registry.Build(PipelineConfig{}, StrategyDeps{}); err == nil {
+			t.Error("Build() error = nil, want error")
+		}
+	})
+}
+
+func TestDefaultStrategyRegistry_LLMFallback(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name  string
+		allow *bool
+		want  bool
+	}{
+		{"allowed by default", nil, true},
+		{"disabled", &disabled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, err := DefaultStrategyRegistry().Build(PipelineConfig{
+				Strategies:       []StrategyConfig{{Name: StrategyFirecrawl}},
+				AllowLLMFallback: tt.allow,
+			}, StrategyDeps{})
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			firecrawl, ok := executor.strategies[0].(*FirecrawlStrategy)
+			if !ok {
+				t.Fatalf("strategy = %T, want *FirecrawlStrategy", executor.strategies[0])
+			}
+			if firecrawl.allowLLM != tt.want {
+				t.Errorf("allowLLM = %v, want %v", firecrawl.allowLLM, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutStrategy(t *testing.T) {
+	inner := &fakeStrategy{name: "inner", block: true}
+	strategy := &timeoutStrategy{FetchStrategy: inner, timeout: 10 * time.Millisecond}
+
+	if strategy.Name() != "inner" {
+		t.Errorf("Name() = %q, want inner", strategy.Name())
+	}
+	_, err := strategy.Fetch(context.Background(), "https://example.com/recipe")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error = %v, want context.DeadlineExceeded", err)
+	}
+}
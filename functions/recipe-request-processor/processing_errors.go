@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// ErrorCode is a stable, machine-readable failure code stored in the
+// error_code column of the recipe request. The app switches on these values,
+// so existing codes must never be renamed.
+type ErrorCode string
+
+// Error codes for every way processing a recipe request can fail
+const (
+	CodeInvalidURL          ErrorCode = "INVALID_URL"
+	CodeFetchFailed         ErrorCode = "FETCH_FAILED"
+	CodeTimeout             ErrorCode = "TIMEOUT"
+	CodeHTTPError           ErrorCode = "HTTP_ERROR"
+	CodeBlocked             ErrorCode = "BLOCKED"
+	CodeBlockedByRobots     ErrorCode = "BLOCKED_BY_ROBOTS"
+	CodeRateLimited         ErrorCode = "RATE_LIMITED"
+	CodeQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	CodeNoRecipe            ErrorCode = "NO_RECIPE"
+	CodeUnsupportedContent  ErrorCode = "UNSUPPORTED_CONTENT"
+	CodeExtractionFailed    ErrorCode = "EXTRACTION_FAILED"
+	CodeStrategyUnavailable ErrorCode = "STRATEGY_UNAVAILABLE"
+	CodeSaveFailed          ErrorCode = "SAVE_FAILED"
+	CodeDatabaseError       ErrorCode = "DATABASE_ERROR"
+	CodeUnknown             ErrorCode = "UNKNOWN"
+)
+
+// maxErrorMessageLength matches the size of the error_message column
+const maxErrorMessageLength = 1024
+
+var (
+	// ErrExtractionFailed indicates a page was fetched but its content could not be turned into a recipe
+	ErrExtractionFailed = errors.New("recipe extraction failed")
+
+	// ErrStrategyUnavailable indicates a strategy cannot run (e.g. missing API key)
+	ErrStrategyUnavailable = errors.New("strategy unavailable")
+)
+
+// StrategyError attributes an error to the strategy that returned it
+type StrategyError struct {
+	Strategy string
+	Err      error
+}
+
+func (e *StrategyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Strategy, e.Err)
+}
+
+func (e *StrategyError) Unwrap() error {
+	return e.Err
+}
+
+// ProcessingError is a classified failure recorded on the recipe request row
+type ProcessingError struct {
+	Code       ErrorCode
+	Message    string
+	Retryable  bool
+	Strategy   string
+	HTTPStatus int
+	PageClass  PageClass
+	Err        error
+}
+
+func (e *ProcessingError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
+// newProcessingError builds a ProcessingError for failures outside the fetch strategies
+func newProcessingError(code ErrorCode, err error, retryable bool) *ProcessingError {
+	return &ProcessingError{
+		Code:      code,
+		Message:   truncateMessage(err.Error()),
+		Retryable: retryable,
+		Err:       err,
+	}
+}
+
+// classifyFetchError maps the error returned by StrategyExecutor.Execute to a
+// ProcessingError. When several strategies failed, a page classification from
+// any of them is the most useful explanation, unless the fallback was
+// short-circuited by its circuit breaker or refused by the LLM quota;
+// otherwise the last failure wins.
+func classifyFetchError(err error) *ProcessingError {
+	failures := strategyFailures(err)
+
+	primary := failures[len(failures)-1]
+	// Without the fallback, the first strategy's failure doesn't tell whether
+	// the page has a recipe
+	fallbackUnavailable := errorAs[*CircuitOpenError](primary) || errorAs[*QuotaExceededError](primary)
+	for _, failure := range failures {
+		var noRecipe *NoRecipeError
+		if !fallbackUnavailable && errors.As(failure, &noRecipe) {
+			primary = failure
+			break
+		}
+	}
+
+	result := &ProcessingError{
+		Message: truncateMessage(err.Error()),
+		Err:     err,
+	}
+	var strategyErr *StrategyError
+	if errors.As(primary, &strategyErr) {
+		result.Strategy = strategyErr.Strategy
+	}
+
+	var disallowed *DisallowedURLError
+	var unsupported *UnsupportedContentError
+	var robots *RobotsDisallowedError
+	var rateLimited *RateLimitedError
+	var quotaExceeded *QuotaExceededError
+	var noRecipe *NoRecipeError
+	var blocked *BlockedError
+	var statusErr *HTTPStatusError
+	var networkErr *NetworkError
+	var netErr net.Error
+	var urlErr *url.Error
+	switch {
+	case errors.As(primary, &disallowed):
+		result.Code = CodeInvalidURL
+	case errors.As(primary, &robots):
+		result.Code = CodeBlockedByRobots
+		result.PageClass = PageClassBlockedByRobots
+	case errors.As(primary, &rateLimited):
+		result.Code = CodeRateLimited
+		result.Retryable = true
+	case errors.As(primary, &quotaExceeded):
+		// Checked before page classifications: the page had no structured
+		// data and the LLM fallback was refused
+		result.Code = CodeQuotaExceeded
+		result.PageClass = PageClassNeedsManualEntry
+	case errors.As(primary, &unsupported):
+		result.Code = CodeUnsupportedContent
+	case errors.As(primary, &noRecipe):
+		result.Code = CodeNoRecipe
+		result.PageClass = noRecipe.Class
+		result.HTTPStatus = noRecipe.HTTPStatus
+	case errors.As(primary, &blocked):
+		result.Code = CodeBlocked
+		result.HTTPStatus = blocked.StatusCode
+		result.Retryable = true
+	case errors.As(primary, &statusErr):
+		result.Code = CodeHTTPError
+		result.HTTPStatus = statusErr.StatusCode
+		result.Retryable = statusErr.StatusCode >= http.StatusInternalServerError
+	case isTimeout(primary):
+		result.Code = CodeTimeout
+		result.Retryable = true
+	case fallbackUnavailable:
+		result.Code = CodeStrategyUnavailable
+		result.PageClass = PageClassFallbackUnavailable
+		result.Retryable = true
+	case errors.Is(primary, ErrStrategyUnavailable):
+		result.Code = CodeStrategyUnavailable
+		result.Retryable = true
+	case errorAs[*ParseError](primary), errors.Is(primary, ErrExtractionFailed):
+		result.Code = CodeExtractionFailed
+	case errors.As(primary, &networkErr):
+		result.Code = CodeFetchFailed
+		result.Retryable = !networkErr.HostNotFound
+	case errors.As(primary, &netErr), errors.As(primary, &urlErr):
+		result.Code = CodeFetchFailed
+		result.Retryable = true
+	default:
+		result.Code = CodeUnknown
+		result.Retryable = true
+	}
+
+	return result
+}
+
+// strategyFailures splits a joined executor error into its individual failures
+func strategyFailures(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		if errs := joined.Unwrap(); len(errs) > 0 {
+			return errs
+		}
+	}
+	return []error{err}
+}
+
+// truncateMessage shortens an error message to fit the error_message column
+func truncateMessage(msg string) string {
+	if len(msg) <= maxErrorMessageLength {
+		return msg
+	}
+	// Avoid cutting a multi-byte rune in half
+	cut := maxErrorMessageLength
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut]
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestClassifyFetchError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      ErrorCode
+		wantRetryable bool
+		wantStrategy  string
+		wantStatus    int
+		wantClass     PageClass
+	}{
+		{
+			name:          "forbidden is blocked",
+			err:           &StrategyError{Strategy: "HTTPClient", Err: &BlockedError{StatusCode: 403}},
+			wantCode:      CodeBlocked,
+			wantRetryable: true,
+			wantStrategy:  "HTTPClient",
+			wantStatus:    403,
+		},
+		{
+			name:          "rate limited is retryable",
+			err:           &StrategyError{Strategy: "HTTPClient", Err: &RateLimitedError{Host: "example.com", Wait: time.Minute}},
+			wantCode:      CodeRateLimited,
+			wantRetryable: true,
+			wantStrategy:  "HTTPClient",
+		},
+		{
+			name:          "server error is retryable",
+			err:           &StrategyError{Strategy: "HTTPClient", Err: &HTTPStatusError{StatusCode: 503}},
+			wantCode:      CodeHTTPError,
+			wantRetryable: true,
+			wantStrategy:  "HTTPClient",
+			wantStatus:    503,
+		},
+		{
+			name:         "client error is not retryable",
+			err:          &StrategyError{Strategy: "HTTPClient", Err: &HTTPStatusError{StatusCode: 400}},
+			wantCode:     CodeHTTPError,
+			wantStrategy: "HTTPClient",
+			wantStatus:   400,
+		},
+		{
+			name:         "unknown host is not retryable",
+			err:          &StrategyError{Strategy: "HTTPClient", Err: &NetworkError{Err: errors.New("no such host"), HostNotFound: true}},
+			wantCode:     CodeFetchFailed,
+			wantStrategy: "HTTPClient",
+		},
+		{
+			name:         "disallowed URL is not retryable",
+			err:          &StrategyError{Strategy: "HTTPClient", Err: &DisallowedURLError{Reason: "address 127.0.0.1 is not public"}},
+			wantCode:     CodeInvalidURL,
+			wantStrategy: "HTTPClient",
+		},
+		{
+			name:         "unsupported content is not retryable",
+			err:          &StrategyError{Strategy: "HTTPClient", Err: &UnsupportedContentError{ContentType: "application/pdf"}},
+			wantCode:     CodeUnsupportedContent,
+			wantStrategy: "HTTPClient",
+		},
+		{
+			name:          "network error",
+			err:           &StrategyError{Strategy: "HTTPClient", Err: &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}},
+			wantCode:      CodeFetchFailed,
+			wantRetryable: true,
+			wantStrategy:  "HTTPClient",
+		},
+		{
+			name: "page classification wins over later failures",
+			err: errors.Join(
+				&StrategyError{Strategy: "HTTPClient", Err: &NoRecipeError{Class: PageClassPaywall, HTTPStatus: 200}},
+				&StrategyError{Strategy: "Firecrawl", Err: ErrStrategyUnavailable},
+			),
+			wantCode:     CodeNoRecipe,
+			wantStrategy: "HTTPClient",
+			wantStatus:   200,
+			wantClass:    PageClassPaywall,
+		},
+		{
+			name: "last failure wins without classification",
+			err: errors.Join(
+				&StrategyError{Strategy: "HTTPClient", Err: &HTTPStatusError{StatusCode: 403}},
+				&StrategyError{Strategy: "Firecrawl", Err: ErrStrategyUnavailable},
+			),
+			wantCode:      CodeStrategyUnavailable,
+			wantRetryable: true,
+			wantStrategy:  "Firecrawl",
+		},
+		{
+			name: "open circuit breaker wins over page classification",
+			err: errors.Join(
+				&StrategyError{Strategy: "HTTPClient", Err: &NoRecipeError{Class: PageClassNoRecipeFound, HTTPStatus: 200}},
+				&StrategyError{Strategy: "Firecrawl", Err: &CircuitOpenError{Name: "firecrawl"}},
+			),
+			wantCode:      CodeStrategyUnavailable,
+			wantRetryable: true,
+			wantStrategy:  "Firecrawl",
+			wantClass:     PageClassFallbackUnavailable,
+		},
+		{
+			name:          "fetch budget exhausted",
+			err:           &StrategyError{Strategy: "Firecrawl", Err: context.DeadlineExceeded},
+			wantCode:      CodeTimeout,
+			wantRetryable: true,
+			wantStrategy:  "Firecrawl",
+		},
+		{
+			name:         "extraction failure",
+			err:          &StrategyError{Strategy: "Firecrawl", Err: ErrExtractionFailed},
+			wantCode:     CodeExtractionFailed,
+			wantStrategy: "Firecrawl",
+		},
+		{
+			name:          "unknown error",
+			err:           errors.New("something odd"),
+			wantCode:      CodeUnknown,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyFetchError(tt.err)
+
+			if got.Code != tt.wantCode {
+				t.Errorf("Code = %s, want %s", got.Code, tt.wantCode)
+			}
+			if got.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", got.Retryable, tt.wantRetryable)
+			}
+			if got.Strategy != tt.wantStrategy {
+				t.Errorf("Strategy = %q, want %q", got.Strategy, tt.wantStrategy)
+			}
+			if got.HTTPStatus != tt.wantStatus {
+				t.Errorf("HTTPStatus = %d, want %d", got.HTTPStatus, tt.wantStatus)
+			}
+			if got.PageClass != tt.wantClass {
+				t.Errorf("PageClass = %q, want %q", got.PageClass, tt.wantClass)
+			}
+			if got.Message != tt.err.Error() {
+				t.Errorf("Message = %q, want %q", got.Message, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		wantLen int
+	}{
+		{name: "short message unchanged", msg: "boom", wantLen: 4},
+		{name: "long ASCII message cut", msg: strings.Repeat("a", 2000), wantLen: maxErrorMessageLength},
+		{name: "multi-byte rune not split", msg: "a" + strings.Repeat("ε", 1000), wantLen: maxErrorMessageLength - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateMessage(tt.msg)
+			if len(got) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(got), tt.wantLen)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncated message is not valid UTF-8")
+			}
+		})
+	}
+}
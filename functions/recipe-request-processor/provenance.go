@@ -0,0 +1,77 @@
+package handler
+
+// ExtractionMethod identifies the overall technique used to extract a recipe
+type ExtractionMethod string
+
+// Extraction methods reported in provenance
+const (
+	MethodJSONLD    ExtractionMethod = "json-ld"
+	MethodMicrodata ExtractionMethod = "microdata"
+	MethodLLM       ExtractionMethod = "llm"
+	MethodHeuristic ExtractionMethod = "heuristic"
+	MethodMarkdown  ExtractionMethod = "markdown"
+)
+
+// sourceConfidence is the default confidence assigned to a field by source.
+// Structured markup is authored by the site itself; OpenGraph and DOM
+// heuristics are page-level guesses; LLM output may be hallucinated. Markdown
+// sections are the same guess as the DOM heuristics, on cleaner input.
+var sourceConfidence = map[RecipeSource]float64{
+	SourceJSONLD:    0.95,
+	SourceMicrodata: 0.9,
+	SourceOpenGraph: 0.7,
+	SourceLLM:       0.6,
+	SourceMarkdown:  0.55,
+	SourceHeuristic: 0.5,
+}
+
+// sourceMethod maps the source of a recipe's name to the overall extraction method
+var sourceMethod = map[RecipeSource]ExtractionMethod{
+	SourceJSONLD:    MethodJSONLD,
+	SourceMicrodata: MethodMicrodata,
+	SourceOpenGraph: MethodHeuristic,
+	SourceHeuristic: MethodHeuristic,
+	SourceMarkdown:  MethodMarkdown,
+	SourceLLM:       MethodLLM,
+}
+
+// Provenance records where a recipe and each of its fields came from
+type Provenance struct {
+	Strategy string                     `json:"strategy"`
+	Method   ExtractionMethod           `json:"method"`
+	Fields   map[string]FieldProvenance `json:"fields"`
+}
+
+// FieldProvenance records the source and confidence of a single field,
+// keyed in Provenance.Fields by the field's schema.org name
+type FieldProvenance struct {
+	Source     RecipeSource `json:"source"`
+	Confidence float64      `json:"confidence"`
+}
+
+// newProvenance builds provenance from the source of each populated field.
+// The strategy is filled in by StrategyExecutor once a strategy succeeds.
+func newProvenance(fieldSources map[string]RecipeSource) *Provenance {
+	provenance := &Provenance{
+		Method: sourceMethod[fieldSources["name"]],
+		Fields: make(map[string]FieldProvenance, len(fieldSources)),
+	}
+	for key, source := range fieldSources {
+		provenance.Fields[key] = FieldProvenance{
+			Source:     source,
+			Confidence: sourceConfidence[source],
+		}
+	}
+	return provenance
+}
+
+// attributeRecipe sets provenance attributing every populated field of recipe to source
+func attributeRecipe(recipe *Recipe, source RecipeSource) {
+	fieldSources := map[string]RecipeSource{}
+	for _, field := range recipeFields {
+		if field.present(recipe) {
+			fieldSources[field.key] = source
+		}
+	}
+	recipe.Provenance = newProvenance(fieldSources)
+}
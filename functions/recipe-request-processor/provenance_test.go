@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"testing"
+)
+
+func TestNewProvenance(t *testing.T) {
+	tests := []struct {
+		name         string
+		fieldSources map[string]RecipeSource
+		wantMethod   ExtractionMethod
+	}{
+		{
+			name:         "name from JSON-LD",
+			fieldSources: map[string]RecipeSource{"name": SourceJSONLD, "image": SourceOpenGraph},
+			wantMethod:   MethodJSONLD,
+		},
+		{
+			name:         "name from Microdata",
+			fieldSources: map[string]RecipeSource{"name": SourceMicrodata},
+			wantMethod:   MethodMicrodata,
+		},
+		{
+			name:         "name from OpenGraph",
+			fieldSources: map[string]RecipeSource{"name": SourceOpenGraph},
+			wantMethod:   MethodHeuristic,
+		},
+		{
+			name:         "name from LLM",
+			fieldSources: map[string]RecipeSource{"name": SourceLLM},
+			wantMethod:   MethodLLM,
+		},
+		{
+			name:         "name from markdown",
+			fieldSources: map[string]RecipeSource{"name": SourceMarkdown},
+			wantMethod:   MethodMarkdown,
+		},
+		{
+			name:         "no fields",
+			fieldSources: nil,
+			wantMethod:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provenance := newProvenance(tt.fieldSources)
+
+			if provenance.Method != tt.wantMethod {
+				t.Errorf("Method = %q, want %q", provenance.Method, tt.wantMethod)
+			}
+			if len(provenance.Fields) != len(tt.fieldSources) {
+				t.Errorf("Fields = %v, want %d entries", provenance.Fields, len(tt.fieldSources))
+			}
+			for key, source := range tt.fieldSources {
+				field := provenance.Fields[key]
+				if field.Source != source {
+					t.Errorf("Fields[%q].Source = %q, want %q", key, field.Source, source)
+				}
+				if field.Confidence != sourceConfidence[source] {
+					t.Errorf("Fields[%q].Confidence = %v, want %v", key, field.Confidence, sourceConfidence[source])
+				}
+			}
+		})
+	}
+}
+
+func TestSourceConfidenceOrdering(t *testing.T) {
+	order := []RecipeSource{SourceJSONLD, SourceMicrodata, SourceOpenGraph, SourceLLM, SourceMarkdown, SourceHeuristic}
+	for i := 1; i < len(order); i++ {
+		if sourceConfidence[order[i-1]] <= sourceConfidence[order[i]] {
+			t.Errorf("confidence of %q (%v) should exceed %q (%v)",
+				order[i-1], sourceConfidence[order[i-1]], order[i], sourceConfidence[order[i]])
+		}
+	}
+}
+
+func TestParseExtractedRecipe_Provenance(t *testing.T) {
+	recipe, err := parseExtractedRecipe(map[string]any{
+		"name":             "LLM Soup",
+		"recipeIngredient": []any{map[string]any{"ingredients": []any{"water", "salt"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if recipe.Provenance == nil {
+		t.Fatal("Expected provenance but got nil")
+	}
+	if recipe.Provenance.Method != MethodLLM {
+		t.Errorf("Method = %q, want %q", recipe.Provenance.Method, MethodLLM)
+	}
+	for _, key := range []string{"name", "recipeIngredient"} {
+		if got := recipe.Provenance.Fields[key].Source; got != SourceLLM {
+			t.Errorf("Fields[%q].Source = %q, want %q", key, got, SourceLLM)
+		}
+	}
+	if _, ok := recipe.Provenance.Fields["image"]; ok {
+		t.Error("Expected no provenance for missing image")
+	}
+}
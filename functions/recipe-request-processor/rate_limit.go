@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appwrite/sdk-for-go/tablesdb"
+)
+
+// RateLimitTableID is the table holding one token bucket per host
+const RateLimitTableID = "rate_limit"
+
+// Environment variables configuring the per-host rate limit
+const (
+	envRateLimitPerMinute = "FETCH_RATE_LIMIT_PER_MINUTE"
+	envRateLimitBurst     = "FETCH_RATE_LIMIT_BURST"
+)
+
+// maxRateLimitWait is the longest a fetch waits for its host's bucket to
+// refill; beyond that the request fails as rate limited instead
+const maxRateLimitWait = 30 * time.Second
+
+// RateLimit is a token bucket allowing Burst fetches at once per host,
+// refilled at PerMinute fetches per minute
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// DefaultRateLimit is used unless overridden from the environment
+var DefaultRateLimit = RateLimit{
+	PerMinute: 30,
+	Burst:     5,
+}
+
+// RateLimitFromEnv returns DefaultRateLimit with overrides from the environment.
+// Missing or invalid values keep the default; a rate of 0 disables limiting.
+func RateLimitFromEnv() RateLimit {
+	limit := DefaultRateLimit
+	if n, ok := envInt(envRateLimitPerMinute); ok && n >= 0 {
+		limit.PerMinute = n
+	}
+	if n, ok := envInt(envRateLimitBurst); ok && n >= 1 {
+		limit.Burst = n
+	}
+	return limit
+}
+
+// enabled reports whether the limit restricts anything
+func (l RateLimit) enabled() bool {
+	return l.PerMinute > 0
+}
+
+// RateLimitedError reports that a host's bucket would not refill in time
+type RateLimitedError struct {
+	Host string
+	Wait time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit for %s exceeded, next fetch allowed in %s", e.Host, e.Wait.Round(time.Second))
+}
+
+// tokenBucket is the stored state of one host's bucket. Tokens go negative
+// while fetches are queued waiting for a refill.
+type tokenBucket struct {
+	Tokens    float64 `json:"tokens"`
+	UpdatedAt int64   `json:"updated_at"` // Unix milliseconds
+}
+
+// take refills the bucket up to now and takes one token, returning the
+// updated bucket and how long the caller must wait for its token. When that
+// wait exceeds maxWait the token is not taken and ok is false.
+func (b tokenBucket) take(limit RateLimit, now time.Time, maxWait time.Duration) (next tokenBucket, wait time.Duration, ok bool) {
+	perMs := float64(limit.PerMinute) / float64(time.Minute.Milliseconds())
+	tokens := float64(limit.Burst)
+	if b.UpdatedAt > 0 {
+		elapsed := now.UnixMilli() - b.UpdatedAt
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = min(float64(limit.Burst), b.Tokens+float64(elapsed)*perMs)
+	}
+
+	tokens--
+	if tokens < 0 {
+		wait = time.Duration(-tokens/perMs) * time.Millisecond
+	}
+	if wait > maxWait {
+		return b, wait, false
+	}
+	return tokenBucket{Tokens: tokens, UpdatedAt: now.UnixMilli()}, wait, true
+}
+
+// RateLimitStore holds the token buckets so they are shared across executions
+type RateLimitStore interface {
+	// Take takes a token from the bucket for key. It returns how long the
+	// caller must wait for the token, and false if that exceeds maxWait.
+	Take(ctx context.Context, key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error)
+}
+
+// memoryRateLimitStore keeps buckets in memory, shared only within one
+// function instance
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]tokenBucket
+}
+
+// newMemoryRateLimitStore creates an empty memoryRateLimitStore
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]tokenBucket{}}
+}
+
+// Take takes a token from the in-memory bucket for key
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, wait, ok := s.buckets[key].take(limit, time.Now(), maxWait)
+	s.buckets[key] = next
+	return wait, ok, nil
+}
+
+// appwriteRateLimitStore keeps buckets in the rate_limit table so all
+// executions share them. Appwrite has no compare-and-swap, so concurrent
+// executions may occasionally both take the last token.
+type appwriteRateLimitStore struct {
+	tablesdb *tablesdb.TablesDB
+}
+
+// RateLimitStore returns a RateLimitStore backed by the rate_limit table
+func (c *RecipeRequestClient) RateLimitStore() RateLimitStore {
+	return &appwriteRateLimitStore{tablesdb: c.tablesdb}
+}
+
+// Take reads the bucket row for key, takes a token and writes the row back
+func (s *appwriteRateLimitStore) Take(_ context.Context, key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	rowID := rateLimitRowID(key)
+
+	var bucket tokenBucket
+	row, err := s.tablesdb.GetRow(DatabaseID, RateLimitTableID, rowID)
+	switch {
+	case isAppwriteNotFound(err):
+		// First fetch from this host: the bucket starts full
+	case err != nil:
+		return 0, false, fmt.Errorf("failed to read rate limit for %s: %w", key, err)
+	default:
+		if err := row.Decode(&bucket); err != nil {
+			return 0, false, fmt.Errorf("failed to decode rate limit for %s: %w", key, err)
+		}
+	}
+
+	next, wait, ok := bucket.take(limit, time.Now(), maxWait)
+	if !ok {
+		return wait, false, nil
+	}
+	_, err = s.tablesdb.UpsertRow(DatabaseID, RateLimitTableID, rowID, s.tablesdb.WithUpsertRowData(map[string]interface{}{
+		"host":       key,
+		"tokens":     next.Tokens,
+		"updated_at": next.UpdatedAt,
+	}))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to save rate limit for %s: %w", key, err)
+	}
+	return wait, true, nil
+}
+
+// rateLimitRowID derives a valid row ID (at most 36 characters) from a host
+func rateLimitRowID(host string) string {
+	sum := sha256.Sum256([]byte(host))
+	return hex.EncodeToString(sum[:16])
+}
+
+// HostRateLimiter spaces out fetches to the same host so a burst of imports
+// from one site doesn't get us throttled. A nil limiter allows every fetch.
+type HostRateLimiter struct {
+	store  RateLimitStore
+	limit  RateLimit
+	logger *Logger
+}
+
+// NewHostRateLimiter creates a HostRateLimiter keeping its buckets in store
+func NewHostRateLimiter(store RateLimitStore, limit RateLimit, logger *Logger) *HostRateLimiter {
+	return &HostRateLimiter{store: store, limit: limit, logger: logger}
+}
+
+// Wait blocks until a fetch from host is allowed. It returns a
+// RateLimitedError if the wait would exceed maxRateLimitWait or the deadline
+// of ctx. Store failures are logged and let the fetch through.
+func (l *HostRateLimiter) Wait(ctx context.Context, host string) error {
+	if l == nil || !l.limit.enabled() {
+		return nil
+	}
+	host = domainKey(host)
+
+	maxWait := maxRateLimitWait
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = min(maxWait, time.Until(deadline))
+	}
+
+	wait, ok, err := l.store.Take(ctx, host, l.limit, maxWait)
+	if err != nil {
+		l.logWarn("Rate limit unavailable, fetching anyway", map[string]interface{}{"host": host, "error": err.Error()})
+		return nil
+	}
+	if !ok {
+		return &RateLimitedError{Host: host, Wait: wait}
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	l.logInfo("Waiting for rate limit", map[string]interface{}{"host": host, "wait_ms": wait.Milliseconds()})
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return transportError(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// logInfo logs an info message if logger is available
+func (l *HostRateLimiter) logInfo(msg string, fields map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Info("rate_limit", msg, fields)
+	}
+}
+
+// logWarn logs a warning if logger is available
+func (l *HostRateLimiter) logWarn(msg string, fields map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Warn("rate_limit", msg, fields)
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	limit := RateLimit{PerMinute: 60, Burst: 2}
+	now := time.UnixMilli(1_700_000_000_000)
+
+	tests := []struct {
+		name       string
+		bucket     tokenBucket
+		now        time.Time
+		maxWait    time.Duration
+		wantWait   time.Duration
+		wantOK     bool
+		wantTokens float64
+	}{
+		{
+			name:       "new bucket starts full",
+			now:        now,
+			maxWait:    time.Minute,
+			wantOK:     true,
+			wantTokens: 1,
+		},
+		{
+			name:       "last token is taken without waiting",
+			bucket:     tokenBucket{Tokens: 1, UpdatedAt: now.UnixMilli()},
+			now:        now,
+			maxWait:    time.Minute,
+			wantOK:     true,
+			wantTokens: 0,
+		},
+		{
+			name:       "empty bucket waits for the refill",
+			bucket:     tokenBucket{Tokens: 0, UpdatedAt: now.UnixMilli()},
+			now:        now,
+			maxWait:    time.Minute,
+			wantWait:   time.Second,
+			wantOK:     true,
+			wantTokens: -1,
+		},
+		{
+			name:       "queued fetches wait in turn",
+			bucket:     tokenBucket{Tokens: -2, UpdatedAt: now.UnixMilli()},
+			now:        now,
+			maxWait:    time.Minute,
+			wantWait:   3 * time.Second,
+			wantOK:     true,
+			wantTokens: -3,
+		},
+		{
+			name:       "refill is capped at the burst",
+			bucket:     tokenBucket{Tokens: 0, UpdatedAt: now.UnixMilli()},
+			now:        now.Add(time.Hour),
+			maxWait:    time.Minute,
+			wantOK:     true,
+			wantTokens: 1,
+		},
+		{
+			name:       "wait beyond max wait takes no token",
+			bucket:     tokenBucket{Tokens: -5, UpdatedAt: now.UnixMilli()},
+			now:        now,
+			maxWait:    time.Second,
+			wantWait:   6 * time.Second,
+			wantOK:     false,
+			wantTokens: -5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, wait, ok := tt.bucket.take(limit, tt.now, tt.maxWait)
+			if wait != tt.wantWait {
+				t.Errorf("wait = %v, want %v", wait, tt.wantWait)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if next.Tokens != tt.wantTokens {
+				t.Errorf("Tokens = %v, want %v", next.Tokens, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestHostRateLimiter_Wait(t *testing.T) {
+	// One fetch per 50ms after a burst of two
+	limiter := NewHostRateLimiter(newMemoryRateLimitStore(), RateLimit{PerMinute: 1200, Burst: 2}, nil)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx, "www.Example.com"); err != nil {
+			t.Fatalf("Wait() within burst error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("burst waited %v, want no wait", elapsed)
+	}
+
+	// The same host under another spelling shares the bucket
+	if err := limiter.Wait(ctx, "example.com"); err != nil {
+		t.Fatalf("Wait() after burst error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("fetch after burst waited %v, want about 50ms", elapsed)
+	}
+
+	// Other hosts are not affected
+	start = time.Now()
+	if err := limiter.Wait(ctx, "other.example"); err != nil {
+		t.Fatalf("Wait() for other host error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("other host waited %v, want no wait", elapsed)
+	}
+}
+
+func TestHostRateLimiter_ExceedsDeadline(t *testing.T) {
+	limiter := NewHostRateLimiter(newMemoryRateLimitStore(), RateLimit{PerMinute: 1, Burst: 1}, nil)
+	if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("first Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := limiter.Wait(ctx, "example.com")
+
+	var rateLimited *RateLimitedError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("Wait() error = %v, want RateLimitedError", err)
+	}
+	if rateLimited.Host != "example.com" || rateLimited.Wait <= time.Second {
+		t.Errorf("RateLimitedError = %+v, want host example.com and wait beyond the deadline", rateLimited)
+	}
+}
+
+// failingRateLimitStore fails every Take
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimit, time.Duration) (time.Duration, bool, error) {
+	return 0, false, errors.New("store unavailable")
+}
+
+func TestHostRateLimiter_FailsOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *HostRateLimiter
+	}{
+		{"nil limiter", nil},
+		{"disabled limit", NewHostRateLimiter(failingRateLimitStore{}, RateLimit{PerMinute: 0, Burst: 1}, nil)},
+		{"store failure", NewHostRateLimiter(failingRateLimitStore{}, DefaultRateLimit, nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limiter.Wait(context.Background(), "example.com"); err != nil {
+				t.Errorf("Wait() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestRateLimitFromEnv(t *testing.T) {
+	t.Setenv(envRateLimitPerMinute, "0")
+	t.Setenv(envRateLimitBurst, "invalid")
+
+	limit := RateLimitFromEnv()
+
+	if limit.PerMinute != 0 || limit.enabled() {
+		t.Errorf("PerMinute = %d, want 0 (disabled)", limit.PerMinute)
+	}
+	if limit.Burst != DefaultRateLimit.Burst {
+		t.Errorf("Burst = %d, want default %d", limit.Burst, DefaultRateLimit.Burst)
+	}
+}
+
+func TestRateLimitRowID(t *testing.T) {
+	id := rateLimitRowID("a-very-long-subdomain.of-a-recipe-site.example.com")
+	if len(id) > 36 {
+		t.Errorf("rateLimitRowID() length = %d, want at most 36", len(id))
+	}
+	if id != rateLimitRowID("a-very-long-subdomain.of-a-recipe-site.example.com") {
+		t.Error("rateLimitRowID() is not stable")
+	}
+}
@@ -47,6 +47,9 @@ func toRecipeResponse(url string, recipe *Recipe) *RecipeResponse {
 	// Flatten instructions to string array
 	response.Instructions = flattenInstructions(recipe.RecipeInstructions)
 
+	// Expose provenance so the app can highlight low-confidence fields
+	response.Provenance = recipe.Provenance
+
 	return response
 }
 
@@ -180,3 +180,14 @@ func TestToRecipeResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestToRecipeResponse_Provenance(t *testing.T) {
+	provenance := &Provenance{Strategy: "Firecrawl", Method: MethodLLM}
+	recipe := &Recipe{Name: "Soup", Provenance: provenance}
+
+	result := toRecipeResponse("https://example.com/soup", recipe)
+
+	if result.Provenance != provenance {
+		t.Errorf("Provenance = %+v, want %+v", result.Provenance, provenance)
+	}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a strategy retries transient failures
+// (5xx responses, 429 and dropped connections) of a single network call
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles per retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than this is not waited for.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used unless overridden from the environment
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// Environment variables overriding DefaultRetryPolicy
+const (
+	envRetryMaxAttempts = "FETCH_RETRY_MAX_ATTEMPTS"
+	envRetryBaseDelayMs = "FETCH_RETRY_BASE_DELAY_MS"
+	envRetryMaxDelayMs  = "FETCH_RETRY_MAX_DELAY_MS"
+)
+
+// RetryPolicyFromEnv returns DefaultRetryPolicy with overrides from the environment.
+// Missing or invalid values keep the default.
+func RetryPolicyFromEnv() RetryPolicy {
+	policy := DefaultRetryPolicy
+	if n, ok := envInt(envRetryMaxAttempts); ok && n >= 1 {
+		policy.MaxAttempts = n
+	}
+	if ms, ok := envInt(envRetryBaseDelayMs); ok && ms >= 0 {
+		policy.BaseDelay = time.Duration(ms) * time.Millisecond
+	}
+	if ms, ok := envInt(envRetryMaxDelayMs); ok && ms >= 0 {
+		policy.MaxDelay = time.Duration(ms) * time.Millisecond
+	}
+	return policy
+}
+
+// envInt reads an integer environment variable
+func envInt(key string) (int, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// delay returns how long to wait before the given retry (1 for the first),
+// or false when the server asked for a longer wait than the policy allows.
+// Backoff is exponential with equal jitter: between half and the full delay.
+func (p RetryPolicy) delay(retry int, err error) (time.Duration, bool) {
+	if after := retryAfter(err); after > 0 {
+		return after, after <= p.MaxDelay
+	}
+	backoff := p.BaseDelay << (retry - 1)
+	if backoff > p.MaxDelay || backoff < p.BaseDelay {
+		backoff = p.MaxDelay
+	}
+	half := backoff / 2
+	return half + rand.N(backoff-half+1), true
+}
+
+// isTransient reports whether err may succeed when the same call is repeated
+func isTransient(err error) bool {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return blocked.StatusCode == http.StatusTooManyRequests
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return !netErr.HostNotFound
+	}
+	var llmErr *LLMAPIError
+	if errors.As(err, &llmErr) {
+		return llmErr.StatusCode == http.StatusTooManyRequests || llmErr.StatusCode >= http.StatusInternalServerError
+	}
+	var firecrawlErr *FirecrawlAPIError
+	if errors.As(err, &firecrawlErr) {
+		return firecrawlErr.StatusCode == http.StatusTooManyRequests || firecrawlErr.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// retryAfter returns the wait requested by the server's Retry-After header, if any
+func retryAfter(err error) time.Duration {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return blocked.RetryAfter
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error, runs
+// out of attempts or the next delay would overrun the deadline of ctx. Every
+// attempt is recorded in the diagnostics carried by ctx.
+func withRetry[T any](ctx context.Context, policy RetryPolicy, operation string, logInfo func(string, ...map[string]interface{}), fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		result, err := fn()
+
+		record := FetchAttempt{
+			Operation:  operation,
+			Attempt:    attempt,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			record.Error = truncateMessage(err.Error())
+		}
+		recordAttempt(ctx, record)
+
+		if err == nil || attempt >= policy.MaxAttempts || !isTransient(err) {
+			return result, err
+		}
+
+		wait, ok := policy.delay(attempt, err)
+		if deadline, hasDeadline := ctx.Deadline(); !ok || (hasDeadline && time.Now().Add(wait).After(deadline)) {
+			return result, err
+		}
+
+		logInfo("Retrying after transient error", map[string]interface{}{
+			"operation": operation,
+			"attempt":   attempt,
+			"delay_ms":  wait.Milliseconds(),
+			"error":     err.Error(),
+		})
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// envBool reads a boolean environment variable, defaulting to false
+func envBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	errBadGateway := &HTTPStatusError{StatusCode: http.StatusBadGateway}
+
+	tests := []struct {
+		name         string
+		errs         []error // error returned by each attempt; nil means success
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "succeeds first time",
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "transient error then success",
+			errs:         []error{errBadGateway, &NetworkError{Err: errors.New("connection reset")}, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "firecrawl outage then success",
+			errs:         []error{&FirecrawlAPIError{StatusCode: http.StatusServiceUnavailable}, &FirecrawlAPIError{StatusCode: http.StatusTooManyRequests}, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "firecrawl rejection is not retried",
+			errs:         []error{&FirecrawlAPIError{StatusCode: http.StatusPaymentRequired}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "non-transient error is not retried",
+			errs:         []error{&HTTPStatusError{StatusCode: http.StatusBadRequest}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "blocked is not retried",
+			errs:         []error{&BlockedError{StatusCode: http.StatusForbidden}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "gives up after max attempts",
+			errs:         []error{errBadGateway, errBadGateway, errBadGateway, nil},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "retry-after beyond max delay is not waited for",
+			errs:         []error{&BlockedError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}, nil},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := NewDiagnostics()
+			ctx := withDiagnostics(context.Background(), diagnostics, "HTTP Client")
+			attempts := 0
+
+			_, err := withRetry(ctx, policy, "http_get", func(string, ...map[string]interface{}) {}, func() (string, error) {
+				err := tt.errs[attempts]
+				attempts++
+				return "body", err
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(diagnostics.Attempts) != tt.wantAttempts {
+				t.Fatalf("recorded %d attempts, want %d", len(diagnostics.Attempts), tt.wantAttempts)
+			}
+			last := diagnostics.Attempts[len(diagnostics.Attempts)-1]
+			if last.Strategy != "HTTP Client" || last.Operation != "http_get" || last.Attempt != tt.wantAttempts {
+				t.Errorf("last attempt = %+v", last)
+			}
+			if (last.Error != "") != tt.wantErr {
+				t.Errorf("last attempt error = %q, wantErr %v", last.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithRetry_StopsAtDeadline(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	_, err := withRetry(ctx, policy, "http_get", func(string, ...map[string]interface{}) {}, func() (int, error) {
+		attempts++
+		return 0, &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}
+	})
+
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (delay would overrun the deadline)", attempts)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		name    string
+		retry   int
+		err     error
+		wantMin time.Duration
+		wantMax time.Duration
+		wantOK  bool
+	}{
+		{"first retry", 1, &HTTPStatusError{StatusCode: 502}, 50 * time.Millisecond, 100 * time.Millisecond, true},
+		{"third retry doubles twice", 3, &HTTPStatusError{StatusCode: 502}, 200 * time.Millisecond, 400 * time.Millisecond, true},
+		{"capped at max delay", 10, &HTTPStatusError{StatusCode: 502}, 500 * time.Millisecond, time.Second, true},
+		{"retry-after honored", 1, &HTTPStatusError{StatusCode: 503, RetryAfter: 700 * time.Millisecond}, 700 * time.Millisecond, 700 * time.Millisecond, true},
+		{"retry-after too long", 1, &BlockedError{StatusCode: 429, RetryAfter: 5 * time.Second}, 5 * time.Second, 5 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.delay(tt.retry, tt.err)
+			if ok != tt.wantOK {
+				t.Errorf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("delay = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{"empty", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"http date", time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat), 3 * time.Second, 5 * time.Second},
+		{"date in the past", "Wed, 21 Oct 2015 07:28:00 GMT", 0, 0},
+		{"garbage", "soon", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	t.Setenv(envRetryMaxAttempts, "5")
+	t.Setenv(envRetryBaseDelayMs, "200")
+	t.Setenv(envRetryMaxDelayMs, "invalid")
+
+	policy := RetryPolicyFromEnv()
+
+	if policy.MaxAttempts != 5 {
+		t.Errorf("MaxAttempts = %d, want 5", policy.MaxAttempts)
+	}
+	if policy.BaseDelay != 200*time.Millisecond {
+		t.Errorf("BaseDelay = %v, want 200ms", policy.BaseDelay)
+	}
+	if policy.MaxDelay != DefaultRetryPolicy.MaxDelay {
+		t.Errorf("MaxDelay = %v, want default %v", policy.MaxDelay, DefaultRetryPolicy.MaxDelay)
+	}
+}
+
+func TestDiagnostics_JSON(t *testing.T) {
+	diagnostics := NewDiagnostics()
+	recordAttempt(withDiagnostics(context.Background(), diagnostics, "Firecrawl"), FetchAttempt{
+		Operation: "scrape_html",
+		Attempt:   1,
+		Error:     "boom",
+	})
+	// Attempts outside a strategy are not recorded
+	recordAttempt(context.Background(), FetchAttempt{Operation: "ignored"})
+
+	data, err := diagnostics.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+
+	var decoded struct {
+		Attempts []FetchAttempt `json:"attempts"`
+	}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	if len(decoded.Attempts) != 1 || decoded.Attempts[0].Strategy != "Firecrawl" || decoded.Attempts[0].Error != "boom" {
+		t.Errorf("Attempts = %+v", decoded.Attempts)
+	}
+}
+
+// TestFirecrawlStrategy_RetriesGatewayError runs the strategy against a fake
+// Firecrawl API failing once with a 502
+func TestFirecrawlStrategy_RetriesGatewayError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "<html><body>Bad Gateway</body></html>")
+			return
+		}
+		page, _ := json.Marshal(testRecipeHTML)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success":true,"data":{"rawHtml":%s,"metadata":{"statusCode":200}}}`, page)
+	}))
+	defer server.Close()
+
+	strategy := NewFirecrawlStrategy(nil, nil)
+	strategy.apiKey = "test-key"
+	strategy.apiURL = server.URL
+	strategy.retry = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	recipe, err := strategy.Fetch(context.Background(), "https://example.com/recipe")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if recipe == nil || recipe.Name == "" {
+		t.Errorf("Fetch() = %+v, want the recipe", recipe)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("API calls = %d, want 2", got)
+	}
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Robots politeness settings
+const (
+	// envRespectRobots opts into honoring robots.txt: the executor checks it
+	// before any strategy runs and the HTTP client identifies as our bot
+	envRespectRobots = "FETCH_RESPECT_ROBOTS"
+	// botUserAgent is sent instead of the browser User-Agent when robots.txt is honored
+	botUserAgent = "RecipifyBot/1.0"
+	// botProductToken is matched case-insensitively against User-agent lines in robots.txt
+	botProductToken = "recipifybot"
+	// robotsCacheTTL is how long a host's robots.txt is reused
+	robotsCacheTTL = time.Hour
+	// maxRobotsSize is the part of robots.txt parsed, the minimum required by RFC 9309
+	maxRobotsSize = 500 << 10
+	// maxCrawlDelay caps the Crawl-delay honored between requests to a host
+	maxCrawlDelay = 10 * time.Second
+)
+
+// RobotsDisallowedError reports that robots.txt disallows fetching the page for our bot
+type RobotsDisallowedError struct {
+	URL string
+}
+
+func (e *RobotsDisallowedError) Error() string {
+	return fmt.Sprintf("blocked by robots.txt: %s", e.URL)
+}
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the rules from the robots.txt group that applies to our bot
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsGroup is a group of rules for one or more user agents
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots parses robots.txt and returns the rules for the given product
+// token, merging all groups naming it, or else all groups for "*"
+func parseRobots(content, token string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			// An empty Disallow allows everything and adds no rule
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			inAgents = false
+			if seconds, err := strconv.ParseFloat(value, 64); current != nil && err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	rules := mergeGroups(groups, token)
+	if rules == nil {
+		rules = mergeGroups(groups, "*")
+	}
+	if rules == nil {
+		rules = &robotsRules{}
+	}
+	return rules
+}
+
+// mergeGroups combines all groups naming agent, or returns nil if none do
+func mergeGroups(groups []*robotsGroup, agent string) *robotsRules {
+	var rules *robotsRules
+	for _, group := range groups {
+		for _, name := range group.agents {
+			if name != agent {
+				continue
+			}
+			if rules == nil {
+				rules = &robotsRules{}
+			}
+			rules.rules = append(rules.rules, group.rules...)
+			if group.crawlDelay > rules.crawlDelay {
+				rules.crawlDelay = group.crawlDelay
+			}
+			break
+		}
+	}
+	return rules
+}
+
+// allowed reports whether the path (with query) may be fetched. The most
+// specific (longest) matching rule wins and Allow wins ties.
+func (r *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	allow := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPatternMatch matches a robots.txt path pattern, where * matches any
+// sequence of characters and a trailing $ anchors the end of the path
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+// robotsChecker fetches and caches robots.txt per host and spaces out
+// requests to a host by its Crawl-delay
+type robotsChecker struct {
+	mu        sync.Mutex
+	cache     map[string]robotsCacheEntry
+	lastFetch map[string]time.Time
+}
+
+// robotsCacheEntry is a cached robots.txt
+type robotsCacheEntry struct {
+	rules   *robotsRules
+	expires time.Time
+}
+
+// sharedRobotsChecker keeps robots.txt across executions of a warm function instance
+var sharedRobotsChecker = newRobotsChecker()
+
+// newRobotsChecker creates an empty robotsChecker
+func newRobotsChecker() *robotsChecker {
+	return &robotsChecker{
+		cache:     map[string]robotsCacheEntry{},
+		lastFetch: map[string]time.Time{},
+	}
+}
+
+// newRobotsClient returns a client fetching robots.txt like the HTTP client
+// strategy does: through the configured proxy, if any, and never from
+// internal addresses
+func newRobotsClient() *http.Client {
+	transport := http.RoundTripper(newGuardedTransport())
+	if proxy, err := ProxyConfigFromEnv(); err == nil && proxy != nil {
+		transport = newProxyTransport(proxy, net.DefaultResolver.LookupNetIP)
+	}
+	return &http.Client{Transport: transport, CheckRedirect: redirectPolicy(checkURL)}
+}
+
+// check returns the rules for the page's host, or a RobotsDisallowedError
+// if robots.txt disallows the page for our bot. An unreachable robots.txt
+// returns the typed transport or status error, so the import can be retried.
+func (c *robotsChecker) check(ctx context.Context, client *http.Client, page *url.URL) (*robotsRules, error) {
+	rules, err := c.rulesFor(ctx, client, page)
+	if err != nil {
+		return nil, err
+	}
+	path := page.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if page.RawQuery != "" {
+		path += "?" + page.RawQuery
+	}
+	if !rules.allowed(path) {
+		return nil, &RobotsDisallowedError{URL: page.String()}
+	}
+	return rules, nil
+}
+
+// rulesFor returns the cached rules for the page's host, fetching robots.txt when needed
+func (c *robotsChecker) rulesFor(ctx context.Context, client *http.Client, page *url.URL) (*robotsRules, error) {
+	origin := page.Scheme + "://" + page.Host
+
+	c.mu.Lock()
+	entry, ok := c.cache[origin]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.rules, nil
+	}
+
+	rules, err := fetchRobots(ctx, client, origin)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.cache[origin] = robotsCacheEntry{rules: rules, expires: time.Now().Add(robotsCacheTTL)}
+	c.mu.Unlock()
+	return rules, nil
+}
+
+// fetchRobots downloads and parses robots.txt for an origin. Following RFC 9309,
+// a missing robots.txt (4xx) allows everything, while an unreachable one (5xx or
+// network error) allows nothing: it returns the typed error, which is not cached
+// and leaves the page unfetched until the import is retried.
+func fetchRobots(ctx context.Context, client *http.Client, origin string) (*robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots.txt request: %w", err)
+	}
+	req.Header.Set("User-Agent", botUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		content, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robots.txt: %w", transportError(err))
+		}
+		return parseRobots(string(content), botProductToken), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &robotsRules{}, nil
+	}
+	return nil, fmt.Errorf("failed to fetch robots.txt: %w", &HTTPStatusError{StatusCode: resp.StatusCode})
+}
+
+// waitTurn blocks until the host's Crawl-delay has passed since our last
+// request to it, then records this request
+func (c *robotsChecker) waitTurn(ctx context.Context, host string, delay time.Duration) error {
+	if delay > maxCrawlDelay {
+		delay = maxCrawlDelay
+	}
+
+	c.mu.Lock()
+	next := c.lastFetch[host].Add(delay)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	c.lastFetch[host] = next
+	c.mu.Unlock()
+
+	wait := time.Until(next)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return transportError(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRobots = `# Example robots.txt
+User-agent: *
+Disallow: /private/
+Crawl-delay: 1
+
+User-agent: RecipifyBot
+User-agent: OtherBot
+Disallow: /recipes/drafts
+Allow: /recipes/drafts/public
+Disallow: /*.pdf$
+Disallow: /search?
+Crawl-delay: 2.5
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  bool
+	}{
+		{"specific group replaces the * group", botProductToken, "/private/recipe", true},
+		{"other agents use the * group", "somebot", "/private/recipe", false},
+		{"disallowed prefix", botProductToken, "/recipes/drafts/soup", false},
+		{"longer allow wins", botProductToken, "/recipes/drafts/public/soup", true},
+		{"wildcard with end anchor", botProductToken, "/menus/week.pdf", false},
+		{"end anchor does not match longer paths", botProductToken, "/menus/week.pdf.html", true},
+		{"query string rule", botProductToken, "/search?q=soup", false},
+		{"unmatched path is allowed", botProductToken, "/recipes/soup", true},
+		{"robots.txt itself is always allowed", "somebot", "/robots.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(testRobots, tt.token)
+			if got := rules.allowed(tt.path); got != tt.want {
+				t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRobots_CrawlDelay(t *testing.T) {
+	if got := parseRobots(testRobots, botProductToken).crawlDelay; got != 2500*time.Millisecond {
+		t.Errorf("crawlDelay = %v, want 2.5s", got)
+	}
+	if got := parseRobots(testRobots, "somebot").crawlDelay; got != time.Second {
+		t.Errorf("crawlDelay = %v, want 1s", got)
+	}
+	if got := parseRobots("", botProductToken); len(got.rules) != 0 || got.crawlDelay != 0 {
+		t.Errorf("empty robots.txt = %+v, want no rules", got)
+	}
+}
+
+func TestRobotsChecker_Fetch(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		wantAllowed     bool
+		wantCached      bool
+		wantUnreachable bool
+	}{
+		{"rules are applied", http.StatusOK, "User-agent: *\nDisallow: /recipe", false, true, false},
+		{"missing robots.txt allows everything", http.StatusNotFound, "", true, true, false},
+		{"unreachable robots.txt is a retryable failure", http.StatusServiceUnavailable, "", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.URL.Path != "/robots.txt" {
+					t.Errorf("requested %s, want /robots.txt", r.URL.Path)
+				}
+				if got := r.Header.Get("User-Agent"); got != botUserAgent {
+					t.Errorf("User-Agent = %q, want %q", got, botUserAgent)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			checker := newRobotsChecker()
+			page, _ := url.Parse(server.URL + "/recipe/1")
+
+			for i := 0; i < 2; i++ {
+				_, err := checker.check(context.Background(), server.Client(), page)
+				if (err == nil) != tt.wantAllowed {
+					t.Errorf("check() error = %v, wantAllowed %v", err, tt.wantAllowed)
+				}
+				if err != nil && errorAs[*RobotsDisallowedError](err) == tt.wantUnreachable {
+					t.Errorf("check() error = %v, want RobotsDisallowedError: %v", err, !tt.wantUnreachable)
+				}
+				if tt.wantUnreachable && !classifyFetchError(err).Retryable {
+					t.Errorf("classifyFetchError(%v) is not retryable", err)
+				}
+			}
+
+			wantRequests := 2
+			if tt.wantCached {
+				wantRequests = 1
+			}
+			if requests != wantRequests {
+				t.Errorf("robots.txt requests = %d, want %d", requests, wantRequests)
+			}
+		})
+	}
+}
+
+func TestRobotsChecker_NetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	page, _ := url.Parse(server.URL + "/recipe/1")
+	server.Close()
+
+	_, err := newRobotsChecker().check(context.Background(), http.DefaultClient, page)
+	if !errorAs[*NetworkError](err) {
+		t.Fatalf("check() error = %v, want NetworkError", err)
+	}
+	if failure := classifyFetchError(err); failure.Code == CodeBlockedByRobots || !failure.Retryable {
+		t.Errorf("classifyFetchError() = %s retryable=%v, want a retryable fetch failure", failure.Code, failure.Retryable)
+	}
+}
+
+func TestRobotsChecker_WaitTurn(t *testing.T) {
+	checker := newRobotsChecker()
+	delay := 50 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := checker.waitTurn(context.Background(), "example.com", delay); err != nil {
+			t.Fatalf("waitTurn() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("three requests took %v, want at least %v", elapsed, 2*delay)
+	}
+
+	// Other hosts are not delayed
+	start = time.Now()
+	if err := checker.waitTurn(context.Background(), "other.example", delay); err != nil {
+		t.Fatalf("waitTurn() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("first request to another host waited %v", elapsed)
+	}
+}
+
+func TestHTTPClientStrategy_HonorsRobots(t *testing.T) {
+	var pageUserAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: RecipifyBot\nDisallow: /members/"))
+		case "/members/recipe":
+			t.Error("disallowed page was fetched")
+		default:
+			pageUserAgent = r.Header.Get("User-Agent")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(testRecipeHTML))
+		}
+	}))
+	defer server.Close()
+
+	strategy := newLocalHTTPClientStrategy()
+	strategy.robots = newRobotsChecker()
+
+	_, err := strategy.Fetch(context.Background(), server.URL+"/members/recipe")
+	var disallowed *RobotsDisallowedError
+	if !errors.As(err, &disallowed) {
+		t.Fatalf("Fetch() error = %v, want *RobotsDisallowedError", err)
+	}
+	if failure := classifyFetchError(err); failure.Code != CodeBlockedByRobots || failure.PageClass != PageClassBlockedByRobots {
+		t.Errorf("classifyFetchError() = %s/%s, want %s/%s", failure.Code, failure.PageClass, CodeBlockedByRobots, PageClassBlockedByRobots)
+	}
+
+	recipe, err := strategy.Fetch(context.Background(), server.URL+"/recipes/cake")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if recipe.Name != "Chocolate Cake" {
+		t.Errorf("Name = %q, want Chocolate Cake", recipe.Name)
+	}
+	if pageUserAgent != botUserAgent {
+		t.Errorf("User-Agent = %q, want %q", pageUserAgent, botUserAgent)
+	}
+}
+
+func TestStrategyExecutor_Robots(t *testing.T) {
+	var robotsStatus atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(robotsStatus.Load()))
+		w.Write([]byte("User-agent: RecipifyBot\nDisallow: /members/"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		status       int
+		path         string
+		wantCalls    int
+		wantRobots   bool
+		wantRetrying bool
+	}{
+		{name: "allowed page runs the strategies", status: http.StatusOK, path: "/recipes/cake", wantCalls: 1},
+		{name: "disallowed page runs no strategy", status: http.StatusOK, path: "/members/cake", wantRobots: true},
+		{name: "unreachable robots.txt runs no strategy", status: http.StatusBadGateway, path: "/recipes/cake", wantRetrying: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robotsStatus.Store(int32(tt.status))
+			// Firecrawl and the LLM don't check robots.txt themselves
+			firecrawl := &fakeStrategy{name: "Firecrawl", recipe: &Recipe{Name: "Cake"}}
+			executor := NewStrategyExecutor(firecrawl).WithRobots(newRobotsChecker(), server.Client())
+
+			_, err := executor.Execute(context.Background(), server.URL+tt.path, nil)
+			if firecrawl.calls != tt.wantCalls {
+				t.Errorf("strategy calls = %d, want %d", firecrawl.calls, tt.wantCalls)
+			}
+			if errorAs[*RobotsDisallowedError](err) != tt.wantRobots {
+				t.Errorf("Execute() error = %v, want RobotsDisallowedError: %v", err, tt.wantRobots)
+			}
+			if tt.wantRetrying && !classifyFetchError(err).Retryable {
+				t.Errorf("classifyFetchError(%v) is not retryable", err)
+			}
+		})
+	}
+}
@@ -1,40 +1,141 @@
 package handler
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// envHedgeDelay enables hedged execution with the given delay in milliseconds
+const envHedgeDelay = "FETCH_HEDGE_DELAY_MS"
+
+// DefaultFetchBudget is the overall time allowed for fetching a recipe across
+// all strategies. It stays well below the 300s function timeout so the result
+// can always be written back before Appwrite kills the execution.
+const DefaultFetchBudget = 240 * time.Second
+
 // FetchStrategy defines the interface for recipe fetching strategies
 type FetchStrategy interface {
-	// Fetch attempts to fetch and parse a recipe from the given URL
-	Fetch(url string) (*Recipe, error)
-
-	// CanRetry determines if the given error is retryable by the next strategy
-	CanRetry(err error) bool
+	// Fetch attempts to fetch and parse a recipe from the given URL.
+	// It must give up when ctx is done.
+	Fetch(ctx context.Context, url string) (*Recipe, error)
 
 	// Name returns the strategy name for logging purposes
 	Name() string
 }
 
-// StrategyExecutor executes fetch strategies in order until one succeeds
+// StrategyExecutor executes fetch strategies in order until one succeeds.
+// Whether a failure falls back to the next strategy is decided by fallbackPolicy.
 type StrategyExecutor struct {
-	strategies []FetchStrategy
+	strategies  []FetchStrategy
+	diagnostics *Diagnostics
+	// hedgeDelay, when set, starts the next strategy in parallel if the
+	// running ones haven't answered within it. Zero runs strategies one at a
+	// time, which never pays for Firecrawl when the HTTP client succeeds.
+	hedgeDelay time.Duration
+	// router, when set, adapts the order of strategies to the domain and
+	// learns from the outcomes of each execution
+	router *DomainRouter
+	// robots, when set, checks robots.txt with robotsClient before any
+	// strategy runs, so none of them fetches a page disallowed for our bot
+	robots       *robotsChecker
+	robotsClient *http.Client
 }
 
 // NewStrategyExecutor creates a new executor with the given strategies
 func NewStrategyExecutor(strategies ...FetchStrategy) *StrategyExecutor {
-	return &StrategyExecutor{strategies: strategies}
+	return &StrategyExecutor{strategies: strategies, diagnostics: NewDiagnostics()}
 }
 
-// Execute tries each strategy in order until one succeeds or all fail
-func (e *StrategyExecutor) Execute(url string, logger *Logger) (*Recipe, error) {
-	var lastErr error
+// WithHedgeDelay enables hedged execution with the given delay; zero keeps
+// the default sequential mode
+func (e *StrategyExecutor) WithHedgeDelay(delay time.Duration) *StrategyExecutor {
+	e.hedgeDelay = delay
+	return e
+}
+
+// WithRouter lets router reorder or skip strategies based on each domain's history
+func (e *StrategyExecutor) WithRouter(router *DomainRouter) *StrategyExecutor {
+	e.router = router
+	return e
+}
+
+// WithRobots checks robots.txt with checker, fetching it through client,
+// before running any strategy
+func (e *StrategyExecutor) WithRobots(checker *robotsChecker, client *http.Client) *StrategyExecutor {
+	e.robots = checker
+	e.robotsClient = client
+	return e
+}
+
+// HedgeDelayFromEnv returns the hedge delay configured in the environment,
+// or zero (sequential mode) when unset or invalid
+func HedgeDelayFromEnv() time.Duration {
+	if ms, ok := envInt(envHedgeDelay); ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return 0
+}
+
+// Diagnostics returns the attempts made by the strategies during Execute
+func (e *StrategyExecutor) Diagnostics() *Diagnostics {
+	return e.diagnostics
+}
+
+// Execute tries each strategy in order until one succeeds or all fail.
+// When ctx has a deadline, each strategy gets an equal share of the time left,
+// so a slow strategy cannot starve the ones after it. In hedged mode the
+// strategies may overlap instead; see executeHedged. With a router, the
+// strategies run in the order it picks for the domain and the outcomes are
+// recorded for the next execution.
+func (e *StrategyExecutor) Execute(ctx context.Context, url string, logger *Logger) (*Recipe, error) {
+	if err := e.checkRobots(ctx, url); err != nil {
+		if logger != nil {
+			logger.Error("strategy", "Page not fetched: robots.txt", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+
+	strategies := e.router.Route(ctx, url, e.strategies)
+	var outcomes []StrategyOutcome
+	record := func(strategy string, err error, latency time.Duration) {
+		if outcome, ok := strategyOutcome(strategy, err, latency); ok {
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	// The fetch budget may be spent by now, but the history is still worth saving
+	defer func() { e.router.Record(context.WithoutCancel(ctx), url, outcomes) }()
+
+	if e.hedgeDelay > 0 && len(strategies) > 1 {
+		return e.executeHedged(ctx, url, strategies, record, logger)
+	}
+
+	var errs []error
+
+	for i, strategy := range strategies {
+		// Stop once the overall budget is spent
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, &StrategyError{Strategy: strategy.Name(), Err: err})
+			break
+		}
 
-	for i, strategy := range e.strategies {
 		if logger != nil {
 			logger.Info("strategy", "Attempting to fetch recipe", map[string]interface{}{
 				"strategy": strategy.Name(),
 			})
 		}
 
-		recipe, err := strategy.Fetch(url)
+		strategyCtx, cancel := strategyContext(ctx, len(strategies)-i)
+		strategyCtx = withDiagnostics(strategyCtx, e.diagnostics, strategy.Name())
+		start := time.Now()
+		recipe, err := strategy.Fetch(strategyCtx, url)
+		cancel()
+		record(strategy.Name(), err, time.Since(start))
 		if err == nil {
+			stampStrategy(recipe, strategy.Name())
 			if logger != nil {
 				logger.Info("strategy", "Recipe fetched successfully", map[string]interface{}{
 					"strategy": strategy.Name(),
@@ -43,15 +144,17 @@ func (e *StrategyExecutor) Execute(url string, logger *Logger) (*Recipe, error)
 			return recipe, nil
 		}
 
-		lastErr = err
+		errs = append(errs, &StrategyError{Strategy: strategy.Name(), Err: err})
 
 		// Check if we should try the next strategy
-		isLastStrategy := i == len(e.strategies)-1
-		if !isLastStrategy && strategy.CanRetry(err) {
+		isLastStrategy := i == len(strategies)-1
+		rule, fallback := fallbackFor(err)
+		if !isLastStrategy && fallback {
 			if logger != nil {
 				logger.Info("strategy", "Strategy failed with retryable error, trying next", map[string]interface{}{
 					"strategy": strategy.Name(),
 					"error":    err.Error(),
+					"rule":     rule,
 				})
 			}
 			continue
@@ -62,10 +165,167 @@ func (e *StrategyExecutor) Execute(url string, logger *Logger) (*Recipe, error)
 			logger.Error("strategy", "Strategy failed", map[string]interface{}{
 				"strategy": strategy.Name(),
 				"error":    err.Error(),
+				"rule":     rule,
 			})
 		}
 		break
 	}
 
-	return nil, lastErr
+	// Join all strategy errors so callers can inspect each failure (e.g. the
+	// page classification from an earlier strategy) with errors.As
+	return nil, errors.Join(errs...)
+}
+
+// checkRobots returns a RobotsDisallowedError when robots.txt is honored and
+// disallows the page, or the retryable error of an unreachable robots.txt
+func (e *StrategyExecutor) checkRobots(ctx context.Context, rawURL string) error {
+	if e.robots == nil {
+		return nil
+	}
+	page, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	_, err = e.robots.check(ctx, e.robotsClient, page)
+	return err
+}
+
+// hedgeResult is the outcome of one strategy started by executeHedged
+type hedgeResult struct {
+	index   int
+	recipe  *Recipe
+	err     error
+	latency time.Duration
+}
+
+// executeHedged starts the strategies in order, starting the next one early
+// when the running ones haven't answered within the hedge delay, or right away
+// when one fails with an error that falls back. The first success wins and
+// the other strategies are cancelled. Each strategy may use all of the time
+// left in ctx. A failure that doesn't fall back starts no further strategy,
+// as in sequential mode, but the strategies already running are awaited and
+// may still win. Strategies cancelled by stopAll are not recorded.
+func (e *StrategyExecutor) executeHedged(ctx context.Context, url string, strategies []FetchStrategy, record func(string, error, time.Duration), logger *Logger) (*Recipe, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, len(strategies))
+	errs := make([]error, len(strategies))
+	started, running := 0, 0
+	// stopped is set by a failure that doesn't fall back
+	stopped := false
+
+	startNext := func() {
+		index, strategy := started, strategies[started]
+		started++
+		running++
+		if logger != nil {
+			logger.Info("strategy", "Attempting to fetch recipe", map[string]interface{}{
+				"strategy": strategy.Name(),
+				"mode":     "hedged",
+				"running":  running,
+			})
+		}
+		go func() {
+			start := time.Now()
+			recipe, err := strategy.Fetch(withDiagnostics(ctx, e.diagnostics, strategy.Name()), url)
+			results <- hedgeResult{index: index, recipe: recipe, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	// stopAll cancels the strategies still running and waits for them, so no
+	// attempt is recorded after Execute returns
+	stopAll := func() {
+		cancel()
+		for ; running > 0; running-- {
+			<-results
+		}
+	}
+
+	startNext()
+	hedge := time.NewTimer(e.hedgeDelay)
+	defer hedge.Stop()
+
+	for running > 0 {
+		select {
+		case <-hedge.C:
+			if !stopped && started < len(strategies) {
+				if logger != nil {
+					logger.Info("strategy", "No answer within hedge delay, starting next strategy", map[string]interface{}{
+						"hedge_delay_ms": e.hedgeDelay.Milliseconds(),
+					})
+				}
+				startNext()
+				hedge.Reset(e.hedgeDelay)
+			}
+
+		case result := <-results:
+			running--
+			strategy := strategies[result.index]
+			record(strategy.Name(), result.err, result.latency)
+			if result.err == nil {
+				stopAll()
+				stampStrategy(result.recipe, strategy.Name())
+				if logger != nil {
+					logger.Info("strategy", "Recipe fetched successfully", map[string]interface{}{
+						"strategy": strategy.Name(),
+						"mode":     "hedged",
+						"started":  started,
+					})
+				}
+				return result.recipe, nil
+			}
+
+			errs[result.index] = &StrategyError{Strategy: strategy.Name(), Err: result.err}
+			rule, fallback := fallbackFor(result.err)
+			if !fallback {
+				if logger != nil {
+					logger.Error("strategy", "Strategy failed", map[string]interface{}{
+						"strategy": strategy.Name(),
+						"error":    result.err.Error(),
+						"rule":     rule,
+						"running":  running,
+					})
+				}
+				stopped = true
+				continue
+			}
+
+			if logger != nil {
+				logger.Info("strategy", "Strategy failed with retryable error", map[string]interface{}{
+					"strategy": strategy.Name(),
+					"error":    result.err.Error(),
+					"rule":     rule,
+				})
+			}
+			if !stopped && started < len(strategies) {
+				startNext()
+				hedge.Reset(e.hedgeDelay)
+			}
+		}
+	}
+
+	// Errors are joined in strategy order, as in sequential mode
+	return nil, errors.Join(errs...)
+}
+
+// strategyContext derives the context for one strategy, limited to an equal
+// share of the time left in ctx among the remaining strategies
+func strategyContext(ctx context.Context, remainingStrategies int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || remainingStrategies <= 1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remainingStrategies))
+}
+
+// stampStrategy records the winning strategy in the recipe's provenance
+func stampStrategy(recipe *Recipe, strategyName string) {
+	if recipe == nil {
+		return
+	}
+	if recipe.Provenance == nil {
+		recipe.Provenance = newProvenance(nil)
+	}
+	recipe.Provenance.Strategy = strategyName
 }
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeStrategy is a FetchStrategy returning canned results for testing
+type fakeStrategy struct {
+	name     string
+	recipe   *Recipe
+	err      error
+	block    bool          // wait for ctx to be done instead of returning immediately
+	delay    time.Duration // answer only after this delay, unless ctx is done first
+	calls    int
+	deadline time.Time
+	canceled bool
+}
+
+func (s *fakeStrategy) Fetch(ctx context.Context, url string) (*Recipe, error) {
+	s.calls++
+	s.deadline, _ = ctx.Deadline()
+	if s.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.delay > 0 {
+		select {
+		case <-ctx.Done():
+			s.canceled = true
+			return nil, ctx.Err()
+		case <-time.After(s.delay):
+		}
+	}
+	return s.recipe, s.err
+}
+
+func (s *fakeStrategy) Name() string { return s.name }
+
+func TestStrategyExecutor_Execute(t *testing.T) {
+	errBlocked := &BlockedError{StatusCode: 403}
+	errClient := &HTTPStatusError{StatusCode: 400}
+
+	tests := []struct {
+		name          string
+		first         *fakeStrategy
+		second        *fakeStrategy
+		wantErr       error
+		wantStrategy  string
+		wantFirstCall int
+		wantSecCall   int
+	}{
+		{
+			name:          "first strategy succeeds",
+			first:         &fakeStrategy{name: "first", recipe: &Recipe{Name: "Soup"}},
+			second:        &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantStrategy:  "first",
+			wantFirstCall: 1,
+		},
+		{
+			name:          "retryable error falls back",
+			first:         &fakeStrategy{name: "first", err: errBlocked},
+			second:        &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantStrategy:  "second",
+			wantFirstCall: 1,
+			wantSecCall:   1,
+		},
+		{
+			name:          "non-retryable error stops",
+			first:         &fakeStrategy{name: "first", err: errClient},
+			second:        &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantErr:       errClient,
+			wantFirstCall: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewStrategyExecutor(tt.first, tt.second)
+
+			recipe, err := executor.Execute(context.Background(), "https://example.com/recipe", nil)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.first.calls != tt.wantFirstCall || tt.second.calls != tt.wantSecCall {
+				t.Errorf("calls = (%d, %d), want (%d, %d)", tt.first.calls, tt.second.calls, tt.wantFirstCall, tt.wantSecCall)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if recipe.Provenance == nil || recipe.Provenance.Strategy != tt.wantStrategy {
+				t.Errorf("Provenance = %+v, want strategy %q", recipe.Provenance, tt.wantStrategy)
+			}
+		})
+	}
+}
+
+func TestStrategyExecutor_ExecuteBudget(t *testing.T) {
+	t.Run("slow strategy does not starve the fallback", func(t *testing.T) {
+		first := &fakeStrategy{name: "first", block: true}
+		second := &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}}
+		executor := NewStrategyExecutor(first, second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		overall, _ := ctx.Deadline()
+
+		recipe, err := executor.Execute(ctx, "https://example.com/recipe", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if recipe.Provenance.Strategy != "second" {
+			t.Errorf("Strategy = %q, want second", recipe.Provenance.Strategy)
+		}
+		if !first.deadline.Before(overall) {
+			t.Errorf("first strategy deadline %v should be before overall deadline %v", first.deadline, overall)
+		}
+		if !second.deadline.Equal(overall) {
+			t.Errorf("last strategy deadline = %v, want overall deadline %v", second.deadline, overall)
+		}
+	})
+
+	t.Run("expired budget skips remaining strategies", func(t *testing.T) {
+		first := &fakeStrategy{name: "first", recipe: &Recipe{Name: "Soup"}}
+		executor := NewStrategyExecutor(first)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := executor.Execute(ctx, "https://example.com/recipe", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+		if first.calls != 0 {
+			t.Errorf("calls = %d, want 0", first.calls)
+		}
+	})
+}
+
+func TestStrategyExecutor_ExecuteHedged(t *testing.T) {
+	const hedgeDelay = 50 * time.Millisecond
+	errBlocked := &BlockedError{StatusCode: 403}
+	errClient := &HTTPStatusError{StatusCode: 400}
+
+	tests := []struct {
+		name          string
+		first         *fakeStrategy
+		second        *fakeStrategy
+		wantErrs      []error
+		wantStrategy  string
+		wantSecCall   int
+		wantCanceled  bool
+		wantUnderTime time.Duration
+	}{
+		{
+			name:         "fast first strategy wins alone",
+			first:        &fakeStrategy{name: "first", recipe: &Recipe{Name: "Soup"}},
+			second:       &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantStrategy: "first",
+		},
+		{
+			name:          "slow first strategy is hedged and canceled",
+			first:         &fakeStrategy{name: "first", recipe: &Recipe{Name: "Soup"}, delay: time.Second},
+			second:        &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantStrategy:  "second",
+			wantSecCall:   1,
+			wantCanceled:  true,
+			wantUnderTime: 500 * time.Millisecond,
+		},
+		{
+			name:          "failure that falls back starts the next strategy at once",
+			first:         &fakeStrategy{name: "first", err: errBlocked},
+			second:        &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantStrategy:  "second",
+			wantSecCall:   1,
+			wantUnderTime: hedgeDelay,
+		},
+		{
+			name:     "failure that does not fall back stops",
+			first:    &fakeStrategy{name: "first", err: errClient},
+			second:   &fakeStrategy{name: "second", recipe: &Recipe{Name: "Soup"}},
+			wantErrs: []error{errClient},
+		},
+		{
+			name:         "failure that does not fall back waits for the running strategy",
+			first:        &fakeStrategy{name: "first", recipe: &Recipe{Name: "Soup"}, delay: 3 * hedgeDelay},
+			second:       &fakeStrategy{name: "second", err: errClient},
+			wantStrategy: "first",
+			wantSecCall:  1,
+		},
+		{
+			name:        "failure that does not fall back joins the running strategy's error",
+			first:       &fakeStrategy{name: "first", err: errBlocked, delay: 3 * hedgeDelay},
+			second:      &fakeStrategy{name: "second", err: errClient},
+			wantErrs:    []error{errBlocked, errClient},
+			wantSecCall: 1,
+		},
+		{
+			name:        "all strategies fail",
+			first:       &fakeStrategy{name: "first", err: errBlocked, delay: 2 * hedgeDelay},
+			second:      &fakeStrategy{name: "second", err: &TimeoutError{Err: context.DeadlineExceeded}},
+			wantErrs:    []error{errBlocked, context.DeadlineExceeded},
+			wantSecCall: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewStrategyExecutor(tt.first, tt.second).WithHedgeDelay(hedgeDelay)
+
+			start := time.Now()
+			recipe, err := executor.Execute(context.Background(), "https://example.com/recipe", nil)
+			elapsed := time.Since(start)
+
+			if tt.first.calls != 1 || tt.second.calls != tt.wantSecCall {
+				t.Errorf("calls = (%d, %d), want (1, %d)", tt.first.calls, tt.second.calls, tt.wantSecCall)
+			}
+			if tt.first.canceled != tt.wantCanceled {
+				t.Errorf("first canceled = %v, want %v", tt.first.canceled, tt.wantCanceled)
+			}
+			if tt.wantUnderTime > 0 && elapsed >= tt.wantUnderTime {
+				t.Errorf("Execute took %v, want under %v", elapsed, tt.wantUnderTime)
+			}
+			if len(tt.wantErrs) > 0 {
+				for _, want := range tt.wantErrs {
+					if !errors.Is(err, want) {
+						t.Errorf("err = %v, want it to include %v", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if recipe.Provenance == nil || recipe.Provenance.Strategy != tt.wantStrategy {
+				t.Errorf("Provenance = %+v, want strategy %q", recipe.Provenance, tt.wantStrategy)
+			}
+		})
+	}
+}
+
+func TestHedgeDelayFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"invalid", 0},
+		{"2500", 2500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(envHedgeDelay, tt.value)
+			if got := HedgeDelayFromEnv(); got != tt.want {
+				t.Errorf("HedgeDelayFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
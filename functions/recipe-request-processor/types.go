@@ -18,8 +18,16 @@ type Recipe struct {
 	RecipeCuisine      []string            `json:"recipeCuisine,omitempty"`
 	Nutrition          *Nutrition          `json:"nutrition,omitempty"`
 	Keywords           *string             `json:"keywords,omitempty"`
+	SuitableForDiet    []string            `json:"suitableForDiet,omitempty"`
 	DatePublished      *string             `json:"datePublished,omitempty"`
 	DateModified       *string             `json:"dateModified,omitempty"`
+
+	// Provenance records which strategy and source produced each field
+	Provenance *Provenance `json:"-"`
+
+	// CanonicalURL is the canonical URL of the page the recipe came from,
+	// preferring the page's own <link rel=canonical>
+	CanonicalURL string `json:"-"`
 }
 
 // Person represents a schema.org Person
@@ -54,15 +62,21 @@ type RecipeInstruction struct {
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Hint      string `json:"hint,omitempty"`
 }
 
 // RecipeResponse is the custom API response format
 type RecipeResponse struct {
-	URL          string        `json:"url"`
-	Recipe       RecipeDetails `json:"recipe"`
-	Instructions []string      `json:"instructions"`
-	Ingredients  []string      `json:"ingredients"`
+	URL          string              `json:"url"`
+	Recipe       RecipeDetails       `json:"recipe"`
+	Instructions []string            `json:"instructions"`
+	Ingredients  []string            `json:"ingredients"`
+	Provenance   *Provenance         `json:"provenance,omitempty"`
+	Completeness *CompletenessReport `json:"completeness,omitempty"`
 }
 
 // RecipeDetails contains the flattened recipe metadata
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects caps the redirect hops followed when fetching a page
+const maxRedirects = 5
+
+// DisallowedURLError reports a URL the processor refuses to fetch, because it
+// is not http(s) or leads to a private, loopback, link-local or metadata address
+type DisallowedURLError struct {
+	Reason string
+}
+
+func (e *DisallowedURLError) Error() string {
+	return "disallowed URL: " + e.Reason
+}
+
+// disallowedPrefixes are non-public ranges not covered by the netip.Addr
+// helpers used in isDisallowedAddr
+var disallowedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach internal IPv4 addresses
+}
+
+// isDisallowedAddr reports whether addr is a private, loopback, link-local
+// (including the 169.254.169.254 metadata service), multicast or reserved address
+func isDisallowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range disallowedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFetchURL checks a user-submitted URL before any strategy fetches it
+func validateFetchURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &DisallowedURLError{Reason: "malformed URL"}
+	}
+	return checkURL(u)
+}
+
+// checkURL validates the scheme and host of a URL before a request is sent.
+// Hostnames are checked again on the resolved address by the dialer.
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &DisallowedURLError{Reason: fmt.Sprintf("scheme %q is not allowed", u.Scheme)}
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return &DisallowedURLError{Reason: "missing host"}
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &DisallowedURLError{Reason: fmt.Sprintf("host %q is not public", host)}
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && isDisallowedAddr(addr) {
+		return &DisallowedURLError{Reason: fmt.Sprintf("address %s is not public", addr)}
+	}
+	return nil
+}
+
+// checkDialAddress refuses connections to disallowed addresses. It runs on the
+// resolved IP, so hostnames resolving to internal addresses (including DNS
+// rebinding after validation) are rejected as well.
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &DisallowedURLError{Reason: fmt.Sprintf("invalid address %q", address)}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || isDisallowedAddr(addr) {
+		return &DisallowedURLError{Reason: fmt.Sprintf("address %s is not public", host)}
+	}
+	return nil
+}
+
+// newGuardedTransport returns a transport that only connects to public
+// addresses and ignores proxy settings from the environment
+func newGuardedTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// redirectPolicy returns an http.Client CheckRedirect validating every
+// redirect hop with validate and capping the number of redirects
+func redirectPolicy(validate func(u *url.URL) error) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return &DisallowedURLError{Reason: fmt.Sprintf("more than %d redirects", maxRedirects)}
+		}
+		return validate(req.URL)
+	}
+}
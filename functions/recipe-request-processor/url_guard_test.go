@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsDisallowedAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"169.254.169.254", true},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isDisallowedAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isDisallowedAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFetchURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/recipe", false},
+		{"http://example.com:8080/recipe", false},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://localhost:3000/", true},
+		{"http://api.localhost/", true},
+		{"http://LOCALHOST./", true},
+		{"http://[::1]/", true},
+		{"http://10.0.0.5/admin", true},
+		{"file:///etc/passwd", true},
+		{"ftp://example.com/recipe", true},
+		{"gopher://example.com", true},
+		{"https:///recipe", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateFetchURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateFetchURL(%q) = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+			if err != nil && !errorAs[*DisallowedURLError](err) {
+				t.Errorf("error type = %T, want *DisallowedURLError", err)
+			}
+		})
+	}
+}
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+		{"127.0.0.1:80", true},
+		{"169.254.169.254:80", true},
+		{"[::1]:443", true},
+		{"not-an-address", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := checkDialAddress("tcp", tt.address, nil); (err != nil) != tt.wantErr {
+				t.Errorf("checkDialAddress(%q) = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	request := func(rawURL string) *http.Request {
+		u, _ := url.Parse(rawURL)
+		return &http.Request{URL: u}
+	}
+	via := func(n int) []*http.Request {
+		return make([]*http.Request, n)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		hops    int
+		wantErr bool
+	}{
+		{"public redirect", "https://example.com/recipe", 1, false},
+		{"redirect to metadata service", "http://169.254.169.254/", 1, true},
+		{"redirect to other scheme", "file:///etc/passwd", 1, true},
+		{"too many redirects", "https://example.com/recipe", maxRedirects, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := redirectPolicy(checkURL)(request(tt.target), via(tt.hops)); (err != nil) != tt.wantErr {
+				t.Errorf("redirectPolicy() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPClientStrategy_RefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the loopback server")
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClientStrategy(nil, nil, nil).Fetch(context.Background(), server.URL)
+
+	var disallowed *DisallowedURLError
+	if !errors.As(err, &disallowed) {
+		t.Errorf("Fetch() error = %v, want *DisallowedURLError", err)
+	}
+	if _, fallback := fallbackFor(err); fallback {
+		t.Error("disallowed URL should not fall back to another strategy")
+	}
+}
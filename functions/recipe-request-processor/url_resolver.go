@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// resolveTimeout bounds expanding a short link, including every hop
+const resolveTimeout = 15 * time.Second
+
+// maxResolvePageSize caps how much of an interstitial or pin page is read
+const maxResolvePageSize = 1 << 20
+
+// shortenerHosts are link shorteners whose redirect is followed to find the
+// page they point to
+var shortenerHosts = map[string]bool{
+	"bit.ly": true, "bitly.com": true, "t.co": true, "pin.it": true, "tinyurl.com": true,
+	"ow.ly": true, "buff.ly": true, "goo.gl": true, "is.gd": true, "rebrand.ly": true,
+	"fb.me": true, "lnkd.in": true, "dlvr.it": true, "trib.al": true, "cutt.ly": true,
+	"shorturl.at": true, "tiny.cc": true, "t.ly": true, "flip.it": true,
+}
+
+// redirectWrapper is a share or click-tracking URL carrying the target URL
+// in a query parameter
+type redirectWrapper struct {
+	hosts  []string
+	path   string
+	params []string
+}
+
+// redirectWrappers are unwrapped without a request
+var redirectWrappers = []redirectWrapper{
+	{[]string{"l.facebook.com", "lm.facebook.com", "facebook.com", "www.facebook.com", "m.facebook.com"}, "/l.php", []string{"u"}},
+	{[]string{"l.messenger.com"}, "/l.php", []string{"u"}},
+	{[]string{"l.instagram.com"}, "/", []string{"u"}},
+	{[]string{"google.com", "www.google.com"}, "/url", []string{"q", "url"}},
+	{[]string{"pinterest.com", "www.pinterest.com"}, "/offsite/", []string{"url"}},
+}
+
+// metaRefreshURL matches the target of a <meta http-equiv=refresh> content value
+var metaRefreshURL = regexp.MustCompile(`(?i)url\s*=\s*['"]?([^'"]+)`)
+
+// pinLinkJSON matches the outbound link in the data embedded in a pin page
+var pinLinkJSON = regexp.MustCompile(`"link"\s*:\s*"(https?:[^"]+)"`)
+
+// URLResolver expands short links, redirect wrappers and Pinterest pins to
+// the URL of the page they lead to, so strategies fetch the recipe page
+// itself. Every hop is checked against internal addresses.
+type URLResolver struct {
+	logger *Logger
+	// transport, validateURL and shorteners are relaxed by tests to reach
+	// httptest servers
+	transport   http.RoundTripper
+	validateURL func(u *url.URL) error
+	shorteners  map[string]bool
+}
+
+// NewURLResolver creates a URLResolver with the given logger
+func NewURLResolver(logger *Logger) *URLResolver {
+	return &URLResolver{
+		logger:      logger,
+		transport:   newGuardedTransport(),
+		validateURL: checkURL,
+		shorteners:  shortenerHosts,
+	}
+}
+
+// Resolve returns the URL to fetch for the page rawURL leads to. URLs that
+// are not short links, wrappers or pins are returned without tracking
+// parameters, keeping mobile and AMP hosts. More than maxRedirects
+// hops or a hop to an internal address fail with a DisallowedURLError.
+func (r *URLResolver) Resolve(ctx context.Context, rawURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	// Redirects are followed one hop at a time so each can be checked
+	client := &http.Client{
+		Transport: r.transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", &DisallowedURLError{Reason: "malformed URL"}
+	}
+	for hop := 0; ; hop++ {
+		if err := r.validateURL(u); err != nil {
+			return "", err
+		}
+
+		next, err := r.nextHop(ctx, client, u)
+		if err != nil {
+			return "", err
+		}
+		if next == nil {
+			return fetchableURL(u).String(), nil
+		}
+		if hop >= maxRedirects {
+			return "", &DisallowedURLError{Reason: fmt.Sprintf("more than %d redirects", maxRedirects)}
+		}
+
+		r.logInfo("Resolved link hop", map[string]interface{}{"from": u.String(), "to": next.String()})
+		u = next
+	}
+}
+
+// nextHop returns the URL that u leads to, or nil if u is the final page
+func (r *URLResolver) nextHop(ctx context.Context, client *http.Client, u *url.URL) (*url.URL, error) {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if target := unwrapRedirect(host, u); target != nil {
+		return target, nil
+	}
+	if isPinterestPin(host, u) {
+		body, _, err := r.get(ctx, client, u)
+		if err != nil {
+			return nil, err
+		}
+		link := pinOutboundLink(body)
+		if link == nil {
+			r.logInfo("Pin has no outbound link", map[string]interface{}{"pin": u.String()})
+		}
+		return link, nil
+	}
+	if r.shorteners[host] {
+		body, location, err := r.get(ctx, client, u)
+		if err != nil {
+			return nil, err
+		}
+		if location != nil {
+			return u.ResolveReference(location), nil
+		}
+		// Some shorteners answer browsers with an interstitial page instead
+		if target := metaRefreshTarget(body); target != nil {
+			return u.ResolveReference(target), nil
+		}
+	}
+	return nil, nil
+}
+
+// get requests u without following redirects, returning the redirect
+// location or the start of the page body
+func (r *URLResolver) get(ctx context.Context, client *http.Client, u *url.URL) (string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setBrowserHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location, err := resp.Location()
+		if err != nil {
+			return "", nil, fmt.Errorf("redirect without location: %w", err)
+		}
+		return "", location, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, statusError(resp)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResolvePageSize))
+	if err != nil {
+		return "", nil, transportError(err)
+	}
+	return string(body), nil, nil
+}
+
+// unwrapRedirect returns the target of a known redirect wrapper, or nil
+func unwrapRedirect(host string, u *url.URL) *url.URL {
+	for _, wrapper := range redirectWrappers {
+		// Canonical URLs have their trailing slash trimmed
+		if strings.TrimSuffix(u.Path, "/") != strings.TrimSuffix(wrapper.path, "/") || !containsHost(wrapper.hosts, host) {
+			continue
+		}
+		query := u.Query()
+		for _, param := range wrapper.params {
+			if target, err := url.Parse(query.Get(param)); err == nil && target.IsAbs() {
+				return target
+			}
+		}
+	}
+	return nil
+}
+
+// isPinterestPin reports whether u is a pin on any Pinterest domain
+// (pinterest.com, de.pinterest.com, pinterest.co.uk, ...)
+func isPinterestPin(host string, u *url.URL) bool {
+	if !strings.HasPrefix(u.Path, "/pin/") {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "pinterest" {
+			return true
+		}
+	}
+	return false
+}
+
+// pinOutboundLink returns the source link of a pin from its page: the
+// pinterestapp:source meta tag, og:see_also, or the link in the embedded data
+func pinOutboundLink(html string) *url.URL {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	source, _ := doc.Find("meta[property='pinterestapp:source'], meta[name='pinterestapp:source']").First().Attr("content")
+	candidates := []string{source, metaContent(doc, "og:see_also")}
+	for _, match := range pinLinkJSON.FindAllStringSubmatch(html, -1) {
+		candidates = append(candidates, strings.ReplaceAll(match[1], `\/`, `/`))
+	}
+	for _, candidate := range candidates {
+		link, err := url.Parse(strings.TrimSpace(candidate))
+		if err != nil || !link.IsAbs() || strings.Contains(strings.ToLower(link.Hostname()), "pinterest.") {
+			continue
+		}
+		return link
+	}
+	return nil
+}
+
+// metaRefreshTarget returns the URL of a <meta http-equiv=refresh> tag, or nil
+func metaRefreshTarget(html string) *url.URL {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	var target *url.URL
+	doc.Find("meta[http-equiv]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		equiv, _ := s.Attr("http-equiv")
+		if !strings.EqualFold(equiv, "refresh") {
+			return true
+		}
+		content, _ := s.Attr("content")
+		if match := metaRefreshURL.FindStringSubmatch(content); match != nil {
+			if u, err := url.Parse(strings.TrimSpace(match[1])); err == nil {
+				target = u
+			}
+		}
+		return false
+	})
+	return target
+}
+
+// containsHost reports whether hosts contains host
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// logInfo logs an info message if logger is available
+func (r *URLResolver) logInfo(msg string, fields map[string]interface{}) {
+	if r.logger != nil {
+		r.logger.Info("url_resolver", msg, fields)
+	}
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newLocalURLResolver returns a URLResolver treating httptest servers on
+// loopback as shorteners while still refusing other internal addresses
+func newLocalURLResolver() *URLResolver {
+	resolver := NewURLResolver(nil)
+	resolver.transport = http.DefaultTransport
+	resolver.validateURL = func(u *url.URL) error {
+		if u.Hostname() == "127.0.0.1" {
+			return nil
+		}
+		return checkURL(u)
+	}
+	resolver.shorteners = map[string]bool{"127.0.0.1": true}
+	return resolver
+}
+
+func TestUnwrapRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"facebook l.php", "https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2Fsoup&h=AT0", "https://example.com/soup"},
+		{"facebook mobile l.php", "https://lm.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2Fsoup", "https://example.com/soup"},
+		{"instagram", "https://l.instagram.com/?u=https%3A%2F%2Fexample.com%2Fsoup&e=AT1", "https://example.com/soup"},
+		{"google url", "https://www.google.com/url?sa=t&url=https%3A%2F%2Fexample.com%2Fsoup", "https://example.com/soup"},
+		{"pinterest offsite", "https://www.pinterest.com/offsite/?token=1&url=https%3A%2F%2Fexample.com%2Fsoup", "https://example.com/soup"},
+		{"relative target is ignored", "https://l.facebook.com/l.php?u=%2Fsoup", ""},
+		{"other facebook page", "https://www.facebook.com/groups/recipes", ""},
+		{"unknown host", "https://example.com/l.php?u=https%3A%2F%2Fexample.org", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Wrappers are recognized as submitted and in their canonical form
+			canonical, err := canonicalURLString(tt.url)
+			if err != nil {
+				t.Fatalf("canonicalURLString() error = %v", err)
+			}
+			for _, raw := range []string{tt.url, canonical} {
+				u, _ := url.Parse(raw)
+				got := ""
+				if target := unwrapRedirect(u.Hostname(), u); target != nil {
+					got = target.String()
+				}
+				if got != tt.want {
+					t.Errorf("unwrapRedirect(%q) = %q, want %q", raw, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsPinterestPin(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://www.pinterest.com/pin/123456/", true},
+		{"https://de.pinterest.com/pin/123456/", true},
+		{"https://pinterest.co.uk/pin/123456/", true},
+		{"https://www.pinterest.com/someone/recipes/", false},
+		{"https://notpinterest.com/pin/123456/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			if got := isPinterestPin(u.Hostname(), u); got != tt.want {
+				t.Errorf("isPinterestPin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinOutboundLink(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "pinterestapp source meta",
+			html: `<meta property="pinterestapp:source" content="https://example.com/soup"><meta property="og:see_also" content="https://example.org/other">`,
+			want: "https://example.com/soup",
+		},
+		{
+			name: "og see also",
+			html: `<meta property="og:see_also" content="https://example.com/soup">`,
+			want: "https://example.com/soup",
+		},
+		{
+			name: "embedded data skips pinterest links",
+			html: `<script id="__PWS_DATA__">{"pin":{"link":"https:\/\/www.pinterest.com\/pin\/1\/","story":{"link":"https:\/\/example.com\/soup"}}}</script>`,
+			want: "https://example.com/soup",
+		},
+		{
+			name: "pin without outbound link",
+			html: `<meta property="og:title" content="Soup">`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if link := pinOutboundLink("<html><head>" + tt.html + "</head></html>"); link != nil {
+				got = link.String()
+			}
+			if got != tt.want {
+				t.Errorf("pinOutboundLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLResolver_Resolve(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/shorter", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/shorter", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://l.facebook.com/l.php?u="+url.QueryEscape("https://example.com/soup/?utm_source=fb"), http.StatusFound)
+	})
+	mux.HandleFunc("/interstitial", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta http-equiv="refresh" content="0;URL='https://example.com/soup'"></head></html>`))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>A shortener landing page</body></html>`))
+	})
+
+	tests := []struct {
+		name           string
+		url            string
+		want           string
+		wantDisallowed bool
+	}{
+		{"regular URL only loses tracking parameters", "https://m.example.com/soup/?utm_source=pinterest", "https://m.example.com/soup/", false},
+		{"shortener chain through a share wrapper", server.URL + "/short", "https://example.com/soup/", false},
+		{"meta refresh interstitial", server.URL + "/interstitial", "https://example.com/soup", false},
+		{"page without redirect is final", server.URL + "/page", server.URL + "/page", false},
+		{"redirect loop is capped", server.URL + "/loop", "", true},
+		{"redirect to metadata service is refused", server.URL + "/metadata", "", true},
+		{"wrapper around internal address is refused", "https://l.facebook.com/l.php?u=" + url.QueryEscape("http://localhost/admin"), "", true},
+	}
+
+	resolver := newLocalURLResolver()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.url)
+			if tt.wantDisallowed {
+				var disallowed *DisallowedURLError
+				if !errors.As(err, &disallowed) {
+					t.Fatalf("Resolve() error = %v, want DisallowedURLError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appwrite/sdk-for-go/id"
+	"github.com/appwrite/sdk-for-go/query"
+	"github.com/appwrite/sdk-for-go/tablesdb"
+)
+
+// UsageTableID is the table metering every Firecrawl call and model call of
+// the llm strategy
+const UsageTableID = "firecrawl_usage"
+
+// Metered Firecrawl operations
+const (
+	// UsageScrape fetches the raw HTML of a page
+	UsageScrape = "scrape"
+	// UsageExtract runs Firecrawl's LLM JSON extraction, the expensive path
+	UsageExtract = "extract"
+	// UsageLLM runs the llm strategy's own model; it costs no Firecrawl credits
+	UsageLLM = "llm"
+)
+
+// llmOperations are the operations counted against the LLM quota
+var llmOperations = []string{UsageExtract, UsageLLM}
+
+// usageCredits are the Firecrawl credits charged per successful call
+var usageCredits = map[string]int{
+	UsageScrape:  1,
+	UsageExtract: 5,
+}
+
+// Environment variables overriding DefaultLLMQuota
+const (
+	envLLMQuotaDaily   = "LLM_QUOTA_DAILY"
+	envLLMQuotaMonthly = "LLM_QUOTA_MONTHLY"
+)
+
+// LLMQuota limits how many LLM extractions each user may trigger per UTC
+// day and month. A limit of 0 is unlimited.
+type LLMQuota struct {
+	Daily   int
+	Monthly int
+}
+
+// DefaultLLMQuota is used unless overridden from the environment
+var DefaultLLMQuota = LLMQuota{
+	Daily:   10,
+	Monthly: 100,
+}
+
+// LLMQuotaFromEnv returns DefaultLLMQuota with overrides from the environment.
+// Missing or invalid values keep the default.
+func LLMQuotaFromEnv() LLMQuota {
+	quota := DefaultLLMQuota
+	if n, ok := envInt(envLLMQuotaDaily); ok && n >= 0 {
+		quota.Daily = n
+	}
+	if n, ok := envInt(envLLMQuotaMonthly); ok && n >= 0 {
+		quota.Monthly = n
+	}
+	return quota
+}
+
+// QuotaExceededError reports that a user used up their LLM extractions for
+// the period, so the recipe has to be entered manually
+type QuotaExceededError struct {
+	Period string // "daily" or "monthly"
+	Limit  int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s LLM extraction quota of %d exceeded", e.Period, e.Limit)
+}
+
+// UsageRecord is one metered Firecrawl call
+type UsageRecord struct {
+	UserID    string
+	RequestID string
+	Operation string
+	Success   bool
+	// Credits charged; failed calls are not charged
+	Credits   int
+	CreatedAt time.Time
+}
+
+// UsageStore holds usage records so quotas apply across executions
+type UsageStore interface {
+	// Add stores a usage record
+	Add(ctx context.Context, record UsageRecord) error
+	// CountSince counts the successful calls of operation by userID since the given time
+	CountSince(ctx context.Context, userID, operation string, since time.Time) (int, error)
+}
+
+// memoryUsageStore keeps usage records in memory, shared only within one
+// function instance
+type memoryUsageStore struct {
+	mu      sync.Mutex
+	records []UsageRecord
+}
+
+// newMemoryUsageStore creates an empty memoryUsageStore
+func newMemoryUsageStore() *memoryUsageStore {
+	return &memoryUsageStore{}
+}
+
+// Add appends the record in memory
+func (s *memoryUsageStore) Add(_ context.Context, record UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// CountSince counts the matching records in memory
+func (s *memoryUsageStore) CountSince(_ context.Context, userID, operation string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, record := range s.records {
+		if record.UserID == userID && record.Operation == operation && record.Success && !record.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// appwriteUsageStore keeps usage records in the firecrawl_usage table
+type appwriteUsageStore struct {
+	tablesdb *tablesdb.TablesDB
+}
+
+// UsageStore returns a UsageStore backed by the firecrawl_usage table
+func (c *RecipeRequestClient) UsageStore() UsageStore {
+	return &appwriteUsageStore{tablesdb: c.tablesdb}
+}
+
+// Add creates a usage row. Rows are only readable with an API key.
+func (s *appwriteUsageStore) Add(_ context.Context, record UsageRecord) error {
+	_, err := s.tablesdb.CreateRow(DatabaseID, UsageTableID, id.Unique(), map[string]interface{}{
+		"user_id":    record.UserID,
+		"request_id": record.RequestID,
+		"operation":  record.Operation,
+		"success":    record.Success,
+		"credits":    record.Credits,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record %s usage: %w", record.Operation, err)
+	}
+	return nil
+}
+
+// CountSince counts the matching rows by their creation time
+func (s *appwriteUsageStore) CountSince(_ context.Context, userID, operation string, since time.Time) (int, error) {
+	rows, err := s.tablesdb.ListRows(DatabaseID, UsageTableID, s.tablesdb.WithListRowsQueries([]string{
+		query.Equal("user_id", userID),
+		query.Equal("operation", operation),
+		query.Equal("success", true),
+		query.GreaterThanEqual("$createdAt", since.UTC().Format(time.RFC3339)),
+		query.Limit(1),
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count %s usage: %w", operation, err)
+	}
+	return rows.Total, nil
+}
+
+// usageKey is the context key for the user and request Firecrawl calls are
+// attributed to
+type usageKey struct{}
+
+// usageSubject is who a metered call is attributed to
+type usageSubject struct {
+	userID    string
+	requestID string
+}
+
+// withUsageSubject returns a context attributing metered calls to the user
+// and recipe request
+func withUsageSubject(ctx context.Context, userID, requestID string) context.Context {
+	return context.WithValue(ctx, usageKey{}, usageSubject{userID: userID, requestID: requestID})
+}
+
+// UsageMeter records every Firecrawl and LLM call and enforces the per-user
+// LLM extraction quota, shared by Firecrawl and the llm strategy. Store
+// failures are logged and never fail an import. A nil meter records nothing
+// and allows everything.
+type UsageMeter struct {
+	store  UsageStore
+	quota  LLMQuota
+	logger *Logger
+	now    func() time.Time
+}
+
+// NewUsageMeter creates a UsageMeter keeping its records in store
+func NewUsageMeter(store UsageStore, quota LLMQuota, logger *Logger) *UsageMeter {
+	return &UsageMeter{store: store, quota: quota, logger: logger, now: time.Now}
+}
+
+// Record meters a Firecrawl or LLM call, attributed to the subject carried
+// by ctx
+func (m *UsageMeter) Record(ctx context.Context, operation string, success bool) {
+	if m == nil {
+		return
+	}
+	subject, _ := ctx.Value(usageKey{}).(usageSubject)
+	record := UsageRecord{
+		UserID:    subject.userID,
+		RequestID: subject.requestID,
+		Operation: operation,
+		Success:   success,
+		CreatedAt: m.now(),
+	}
+	if success {
+		record.Credits = usageCredits[operation]
+	}
+	if err := m.store.Add(ctx, record); err != nil {
+		m.logWarn("Error recording usage", map[string]interface{}{"operation": operation, "error": err.Error()})
+	}
+}
+
+// AllowExtraction returns a QuotaExceededError if the user of ctx has used up
+// their daily or monthly LLM extractions. Calls without a user are allowed.
+func (m *UsageMeter) AllowExtraction(ctx context.Context) error {
+	subject, _ := ctx.Value(usageKey{}).(usageSubject)
+	if m == nil || subject.userID == "" {
+		return nil
+	}
+
+	now := m.now().UTC()
+	periods := []struct {
+		name  string
+		limit int
+		since time.Time
+	}{
+		{"daily", m.quota.Daily, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)},
+		{"monthly", m.quota.Monthly, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, period := range periods {
+		if period.limit <= 0 {
+			continue
+		}
+		used := 0
+		for _, operation := range llmOperations {
+			count, err := m.store.CountSince(ctx, subject.userID, operation, period.since)
+			if err != nil {
+				m.logWarn("Usage unavailable, allowing extraction", map[string]interface{}{"error": err.Error()})
+				return nil
+			}
+			used += count
+		}
+		if used >= period.limit {
+			m.logInfo("LLM extraction quota exceeded", map[string]interface{}{
+				"user_id": subject.userID,
+				"period":  period.name,
+				"used":    used,
+				"limit":   period.limit,
+			})
+			return &QuotaExceededError{Period: period.name, Limit: period.limit}
+		}
+	}
+	return nil
+}
+
+// logInfo logs an info message if logger is available
+func (m *UsageMeter) logInfo(msg string, fields map[string]interface{}) {
+	if m.logger != nil {
+		m.logger.Info("usage", msg, fields)
+	}
+}
+
+// logWarn logs a warning if logger is available
+func (m *UsageMeter) logWarn(msg string, fields map[string]interface{}) {
+	if m.logger != nil {
+		m.logger.Warn("usage", msg, fields)
+	}
+}
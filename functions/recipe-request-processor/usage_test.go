@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLLMQuotaFromEnv(t *testing.T) {
+	t.Setenv(envLLMQuotaDaily, "3")
+	t.Setenv(envLLMQuotaMonthly, "invalid")
+
+	got := LLMQuotaFromEnv()
+	want := LLMQuota{Daily: 3, Monthly: DefaultLLMQuota.Monthly}
+	if got != want {
+		t.Errorf("LLMQuotaFromEnv() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUsageMeter_AllowExtraction(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	extraction := func(at time.Time) UsageRecord {
+		return UsageRecord{UserID: "user-1", Operation: UsageExtract, Success: true, CreatedAt: at}
+	}
+
+	tests := []struct {
+		name       string
+		quota      LLMQuota
+		records    []UsageRecord
+		userID     string
+		wantPeriod string
+	}{
+		{
+			name:    "under quota",
+			quota:   LLMQuota{Daily: 2, Monthly: 10},
+			records: []UsageRecord{extraction(now.Add(-time.Hour))},
+			userID:  "user-1",
+		},
+		{
+			name:       "daily quota used up",
+			quota:      LLMQuota{Daily: 1, Monthly: 10},
+			records:    []UsageRecord{extraction(now.Add(-time.Hour))},
+			userID:     "user-1",
+			wantPeriod: "daily",
+		},
+		{
+			name:    "yesterday's extractions don't count for the day",
+			quota:   LLMQuota{Daily: 1, Monthly: 10},
+			records: []UsageRecord{extraction(now.Add(-24 * time.Hour))},
+			userID:  "user-1",
+		},
+		{
+			name:       "monthly quota used up",
+			quota:      LLMQuota{Daily: 5, Monthly: 2},
+			records:    []UsageRecord{extraction(now.Add(-48 * time.Hour)), extraction(now.Add(-72 * time.Hour))},
+			userID:     "user-1",
+			wantPeriod: "monthly",
+		},
+		{
+			name:  "llm strategy extractions count",
+			quota: LLMQuota{Daily: 2, Monthly: 10},
+			records: []UsageRecord{
+				extraction(now.Add(-time.Hour)),
+				{UserID: "user-1", Operation: UsageLLM, Success: true, CreatedAt: now.Add(-time.Minute)},
+			},
+			userID:     "user-1",
+			wantPeriod: "daily",
+		},
+		{
+			name:  "failed and other users' extractions and scrapes don't count",
+			quota: LLMQuota{Daily: 1, Monthly: 1},
+			records: []UsageRecord{
+				{UserID: "user-1", Operation: UsageExtract, Success: false, CreatedAt: now},
+				{UserID: "user-2", Operation: UsageExtract, Success: true, CreatedAt: now},
+				{UserID: "user-1", Operation: UsageScrape, Success: true, CreatedAt: now},
+			},
+			userID: "user-1",
+		},
+		{
+			name:    "unlimited",
+			quota:   LLMQuota{},
+			records: []UsageRecord{extraction(now)},
+			userID:  "user-1",
+		},
+		{
+			name:    "no user",
+			quota:   LLMQuota{Daily: 1},
+			records: []UsageRecord{{Operation: UsageExtract, Success: true, CreatedAt: now}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryUsageStore()
+			store.records = tt.records
+			meter := NewUsageMeter(store, tt.quota, nil)
+			meter.now = func() time.Time { return now }
+
+			err := meter.AllowExtraction(withUsageSubject(context.Background(), tt.userID, "request-1"))
+			var quotaErr *QuotaExceededError
+			switch {
+			case tt.wantPeriod == "" && err != nil:
+				t.Errorf("AllowExtraction() = %v, want nil", err)
+			case tt.wantPeriod != "" && !errors.As(err, &quotaErr):
+				t.Errorf("AllowExtraction() = %v, want QuotaExceededError", err)
+			case tt.wantPeriod != "" && quotaErr.Period != tt.wantPeriod:
+				t.Errorf("Period = %q, want %q", quotaErr.Period, tt.wantPeriod)
+			}
+		})
+	}
+}
+
+// failingUsageStore is a UsageStore that is always unavailable
+type failingUsageStore struct{}
+
+func (failingUsageStore) Add(context.Context, UsageRecord) error {
+	return errors.New("unavailable")
+}
+
+func (failingUsageStore) CountSince(context.Context, string, string, time.Time) (int, error) {
+	return 0, errors.New("unavailable")
+}
+
+func TestUsageMeter_StoreUnavailable(t *testing.T) {
+	meter := NewUsageMeter(failingUsageStore{}, LLMQuota{Daily: 1}, nil)
+	ctx := withUsageSubject(context.Background(), "user-1", "request-1")
+
+	if err := meter.AllowExtraction(ctx); err != nil {
+		t.Errorf("AllowExtraction() = %v, want nil when usage is unavailable", err)
+	}
+	// Must not panic or fail
+	meter.Record(ctx, UsageScrape, true)
+}
+
+// TestFirecrawlStrategy_Usage runs the strategy against a fake Firecrawl API
+// serving a page without structured data
+func TestFirecrawlStrategy_Usage(t *testing.T) {
+	var extractCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "jsonOptions") {
+			extractCalls++
+			fmt.Fprint(w, `{"success":true,"data":{"json":{"name":"Soup","recipeIngredient":[{"ingredients":["water"]}],"recipeInstructions":[{"steps":["Boil"]}]}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"data":{"rawHtml":"<html><body><p>Grandma's soup</p></body></html>","metadata":{"statusCode":200}}}`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	store := newMemoryUsageStore()
+	meter := NewUsageMeter(store, LLMQuota{Daily: 1}, nil)
+	meter.now = func() time.Time { return now }
+
+	strategy := NewFirecrawlStrategy(nil, nil)
+	strategy.apiKey = "test-key"
+	strategy.apiURL = server.URL
+	strategy.retry = RetryPolicy{MaxAttempts: 1}
+	strategy.meter = meter
+	ctx := withUsageSubject(context.Background(), "user-1", "request-1")
+
+	// Within quota: both calls are metered and attributed
+	if _, err := strategy.Fetch(ctx, "https://example.com/soup"); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(store.records) != 2 {
+		t.Fatalf("records = %d, want scrape and extract", len(store.records))
+	}
+	for i, want := range []struct {
+		operation string
+		credits   int
+	}{{UsageScrape, 1}, {UsageExtract, 5}} {
+		record := store.records[i]
+		if record.Operation != want.operation || record.Credits != want.credits || record.UserID != "user-1" || record.RequestID != "request-1" {
+			t.Errorf("record %d = %+v, want %s for %d credits attributed to user-1 and request-1", i, record, want.operation, want.credits)
+		}
+	}
+
+	// Quota used up: no LLM extraction, the user has to enter the recipe
+	_, err := strategy.Fetch(ctx, "https://example.com/soup")
+	if !errorAs[*QuotaExceededError](err) {
+		t.Fatalf("Fetch() error = %v, want QuotaExceededError", err)
+	}
+	if extractCalls != 1 {
+		t.Errorf("extract calls = %d, want 1", extractCalls)
+	}
+	failure := classifyFetchError(errors.Join(
+		&StrategyError{Strategy: "HTTPClient", Err: &NoRecipeError{Class: PageClassNoRecipeFound}},
+		&StrategyError{Strategy: "Firecrawl", Err: err},
+	))
+	if failure.Code != CodeQuotaExceeded || failure.PageClass != PageClassNeedsManualEntry || failure.Retryable {
+		t.Errorf("classifyFetchError() = %+v, want non-retryable %s with %s", failure, CodeQuotaExceeded, PageClassNeedsManualEntry)
+	}
+}
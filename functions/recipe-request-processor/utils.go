@@ -50,6 +50,26 @@ func getStringPtr(obj map[string]interface{}, key string) *string {
 	return nil
 }
 
+// stringPtr returns the sanitized string, or nil if nothing is left
+func stringPtr(s string) *string {
+	sanitized := sanitizeText(s)
+	if sanitized == "" {
+		return nil
+	}
+	return &sanitized
+}
+
+// nonEmptyStrings returns the sanitized strings, dropping empty ones
+func nonEmptyStrings(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if sanitized := sanitizeText(value); sanitized != "" {
+			result = append(result, sanitized)
+		}
+	}
+	return result
+}
+
 func getStringArray(obj map[string]interface{}, key string) []string {
 	val, ok := obj[key]
 	if !ok {
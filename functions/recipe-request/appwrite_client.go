@@ -26,7 +26,7 @@ const (
 
 // RecipeRequestStore defines the interface for recipe request operations
 type RecipeRequestStore interface {
-	CreateRequest(url, userID string) (string, error)
+	CreateRequest(url, canonicalURL, userID string) (string, error)
 }
 
 // RecipeRequestClient handles database operations for recipe requests
@@ -59,12 +59,14 @@ func NewRecipeRequestClient() *RecipeRequestClient {
 	}
 }
 
-// CreateRequest creates a new recipe request record with REQUESTED status
-func (c *RecipeRequestClient) CreateRequest(url, userID string) (string, error) {
+// CreateRequest creates a new recipe request record with REQUESTED status,
+// storing the submitted URL along with its canonical form
+func (c *RecipeRequestClient) CreateRequest(url, canonicalURL, userID string) (string, error) {
 	data := map[string]interface{}{
-		"url":     url,
-		"status":  StatusRequested,
-		"user_id": userID,
+		"url":           url,
+		"canonical_url": canonicalURL,
+		"status":        StatusRequested,
+		"user_id":       userID,
 	}
 
 	// Set permissions to allow only the user who created the request to read it
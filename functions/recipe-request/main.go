@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -54,6 +55,21 @@ func Main(Context openruntimes.Context) openruntimes.Response {
 		}, Context.Res.WithStatusCode(http.StatusBadRequest))
 	}
 
+	// Strip tracking parameters, fragments and mobile/AMP variants so the same
+	// recipe shared in different ways maps to one canonical URL
+	canonicalURL := canonicalizeURL(parsedURL)
+
+	// Refuse URLs leading to internal addresses. The processor fetches the
+	// submitted host, which may be a mobile or AMP one, and enforces this again
+	// on every connection and redirect.
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+	if err := checkRequestURL(ctx, fetchableURL(parsedURL)); err != nil {
+		return Context.Res.Json(ErrorResponse{
+			Error: fmt.Sprintf("URL is not allowed: %v", err),
+		}, Context.Res.WithStatusCode(http.StatusBadRequest))
+	}
+
 	// Create structured logger with request context
 	logger := NewLogger(Context, targetURL, userID)
 	logger.Info("main", "Processing recipe request")
@@ -62,7 +78,7 @@ func Main(Context openruntimes.Context) openruntimes.Response {
 	requestClient := NewRecipeRequestClient()
 
 	// Create request record with REQUESTED status
-	documentID, err := requestClient.CreateRequest(targetURL, userID)
+	documentID, err := requestClient.CreateRequest(targetURL, canonicalURL.String(), userID)
 	if err != nil {
 		logger.Error("main", "Error creating request record", map[string]interface{}{
 			"error": err.Error(),
@@ -73,13 +89,15 @@ func Main(Context openruntimes.Context) openruntimes.Response {
 	}
 
 	logger.WithDuration("main", "Request record created", map[string]interface{}{
-		"document_id": documentID,
+		"document_id":   documentID,
+		"canonical_url": canonicalURL.String(),
 	})
 
 	// Return success response with document ID
 	return Context.Res.Json(SuccessResponse{
-		DocumentID: documentID,
-		Status:     StatusRequested,
-		URL:        targetURL,
+		DocumentID:   documentID,
+		Status:       StatusRequested,
+		URL:          targetURL,
+		CanonicalURL: canonicalURL.String(),
 	})
 }
@@ -23,7 +23,7 @@ func TestCreateRequest_Integration(t *testing.T) {
 	testUserID := "test-user-123"
 	t.Logf("Creating request record for: %s", testURL)
 
-	docID, err := client.CreateRequest(testURL, testUserID)
+	docID, err := client.CreateRequest(testURL, testURL, testUserID)
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
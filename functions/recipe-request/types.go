@@ -7,9 +7,10 @@ type RequestBody struct {
 
 // SuccessResponse represents a successful request creation response
 type SuccessResponse struct {
-	DocumentID string `json:"documentId"`
-	Status     string `json:"status"`
-	URL        string `json:"url"`
+	DocumentID   string `json:"documentId"`
+	Status       string `json:"status"`
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonicalUrl"`
 }
 
 // ErrorResponse represents an error response
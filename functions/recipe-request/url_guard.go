@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dnsLookupTimeout bounds the DNS lookup done while validating a URL
+const dnsLookupTimeout = 3 * time.Second
+
+// disallowedPrefixes are non-public ranges not covered by the netip.Addr
+// helpers used in isDisallowedAddr
+var disallowedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach internal IPv4 addresses
+}
+
+// lookupHost resolves a hostname; replaced in tests
+var lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// isDisallowedAddr reports whether addr is a private, loopback, link-local
+// (including the 169.254.169.254 metadata service), multicast or reserved address
+func isDisallowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range disallowedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequestURL rejects URLs the processor would refuse to fetch: schemes
+// other than http(s) and hosts that are or resolve to non-public addresses.
+// Lookup failures are not rejected here; the processor reports them.
+func checkRequestURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("host %q is not public", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if isDisallowedAddr(addr) {
+			return fmt.Errorf("address %s is not public", addr)
+		}
+		return nil
+	}
+
+	addrs, err := lookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if isDisallowedAddr(addr) {
+			return fmt.Errorf("host %q resolves to non-public address %s", host, addr)
+		}
+	}
+	return nil
+}
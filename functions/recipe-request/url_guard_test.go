@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestCheckRequestURL(t *testing.T) {
+	resolved := map[string][]netip.Addr{
+		"example.com":       {netip.MustParseAddr("93.184.216.34")},
+		"internal.corp.com": {netip.MustParseAddr("10.0.0.7")},
+		"rebind.example":    {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("169.254.169.254")},
+	}
+	original := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if addrs, ok := resolved[host]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookupHost = original })
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/recipe", false},
+		{"https://unresolvable.example/recipe", false},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://127.0.0.1:8080/", true},
+		{"http://[::1]/", true},
+		{"http://localhost/", true},
+		{"http://internal.corp.com/recipe", true},
+		{"http://rebind.example/", true},
+		{"ftp://example.com/recipe", true},
+		{"javascript://example.com/%0aalert(1)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("url.Parse(%q) error = %v", tt.url, err)
+			}
+			if err := checkRequestURL(context.Background(), u); (err != nil) != tt.wantErr {
+				t.Errorf("checkRequestURL(%q) = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -12,6 +12,10 @@ enum RecipeRequestStatus {
   /// Recipe successfully extracted.
   completed('COMPLETED'),
 
+  /// Recipe extracted, but fields such as yield or times are missing and
+  /// should be reviewed by the user.
+  needsReview('NEEDS_REVIEW'),
+
   /// Extraction failed.
   failed('FAILED');
 
@@ -40,6 +44,7 @@ class RecipeRequest {
     required this.userId,
     required this.createdAt,
     required this.updatedAt,
+    this.missingFields = const [],
   });
 
   /// Creates a [RecipeRequest] from an Appwrite Document.
@@ -51,6 +56,7 @@ class RecipeRequest {
       userId: doc.data['user_id'] as String,
       createdAt: DateTime.parse(doc.$createdAt),
       updatedAt: DateTime.parse(doc.$updatedAt),
+      missingFields: _parseMissingFields(doc.data['missing_fields']),
     );
   }
 
@@ -63,9 +69,15 @@ class RecipeRequest {
       userId: map['user_id'] as String,
       createdAt: DateTime.parse(map[r'$createdAt'] as String),
       updatedAt: DateTime.parse(map[r'$updatedAt'] as String),
+      missingFields: _parseMissingFields(map['missing_fields']),
     );
   }
 
+  static List<String> _parseMissingFields(dynamic value) {
+    if (value is! List) return const [];
+    return value.whereType<String>().toList();
+  }
+
   /// Unique identifier for the request.
   final String id;
 
@@ -84,6 +96,10 @@ class RecipeRequest {
   /// When the request was last updated.
   final DateTime updatedAt;
 
+  /// Recipe fields the extraction could not find, set when [status] is
+  /// [RecipeRequestStatus.needsReview].
+  final List<String> missingFields;
+
   /// Creates a copy with the given fields replaced.
   RecipeRequest copyWith({
     String? id,
@@ -92,6 +108,7 @@ class RecipeRequest {
     String? userId,
     DateTime? createdAt,
     DateTime? updatedAt,
+    List<String>? missingFields,
   }) {
     return RecipeRequest(
       id: id ?? this.id,
@@ -100,6 +117,7 @@ class RecipeRequest {
       userId: userId ?? this.userId,
       createdAt: createdAt ?? this.createdAt,
       updatedAt: updatedAt ?? this.updatedAt,
+      missingFields: missingFields ?? this.missingFields,
     );
   }
 
@@ -30,6 +30,7 @@ class HomeController {
   final Signal<RecipeRequest?> _activeRequest = signal<RecipeRequest?>(null);
   final Signal<Recipe?> _previewRecipe = signal<Recipe?>(null);
   final Signal<List<PendingImport>> _pendingImports = signal<List<PendingImport>>([]);
+  final Signal<Map<String, List<String>>> _missingFields = signal<Map<String, List<String>>>({});
 
   /// The list of recipes.
   ReadonlySignal<List<Recipe>> get recipes => _recipes;
@@ -58,6 +59,9 @@ class HomeController {
   /// List of pending imports (loading or failed).
   ReadonlySignal<List<PendingImport>> get pendingImports => _pendingImports;
 
+  /// Fields missing from imported recipes that need review, by recipe ID.
+  ReadonlySignal<Map<String, List<String>>> get missingFields => _missingFields;
+
   /// Loads recipes from the backend.
   Future<void> loadRecipes() async {
     _isLoading.value = true;
@@ -78,6 +82,7 @@ class HomeController {
     try {
       await _importService.deleteRecipe(recipeId);
       _recipes.value = _recipes.value.where((r) => r.id != recipeId).toList();
+      _missingFields.value = {..._missingFields.value}..remove(recipeId);
     } on Exception catch (e) {
       _error.value = 'Failed to delete recipe: $e';
     }
@@ -147,6 +152,9 @@ class HomeController {
             switch (request.status) {
               case RecipeRequestStatus.completed:
                 unawaited(_onImportComplete(requestId));
+              case RecipeRequestStatus.needsReview:
+                // The recipe was created; the user fills in what is missing
+                unawaited(_onImportComplete(requestId, missingFields: request.missingFields));
               case RecipeRequestStatus.failed:
                 _onImportError(requestId, 'Recipe extraction failed');
               case RecipeRequestStatus.requested:
@@ -170,13 +178,16 @@ class HomeController {
     }).toList();
   }
 
-  Future<void> _onImportComplete(String requestId) async {
+  Future<void> _onImportComplete(String requestId, {List<String> missingFields = const []}) async {
     try {
       final recipe = await _importService.getExtractedRecipe(requestId);
       if (recipe != null) {
         // Remove from pending and add to recipes
         _pendingImports.value = _pendingImports.value.where((p) => p.request.id != requestId).toList();
         _recipes.value = [recipe, ..._recipes.value];
+        if (missingFields.isNotEmpty) {
+          _missingFields.value = {..._missingFields.value, recipe.id: missingFields};
+        }
 
         // Update state for backward compatibility
         if (_activeRequest.value?.id == requestId) {
@@ -140,6 +140,7 @@ class _HomePageState extends State<HomePage> with SignalsMixin {
         MaterialPageRoute<void>(
           builder: (_) => RecipePreviewPage(
             recipe: recipe,
+            missingFields: _controller.missingFields.value[recipe.id] ?? const [],
             onSaveOrDelete: () async {
               await _controller.deleteRecipe(recipe.id);
               if (mounted) Navigator.of(context).pop();
@@ -16,6 +16,7 @@ class RecipePreviewPage extends StatefulWidget {
     this.onCancel,
     this.isProcessing = false,
     this.isEditable = false,
+    this.missingFields = const [],
     super.key,
   });
 
@@ -34,6 +35,9 @@ class RecipePreviewPage extends StatefulWidget {
   /// Whether fields are editable (for partial data).
   final bool isEditable;
 
+  /// Fields the import could not find, shown so the user can review them.
+  final List<String> missingFields;
+
   @override
   State<RecipePreviewPage> createState() => _RecipePreviewPageState();
 }
@@ -55,6 +59,10 @@ class _RecipePreviewPageState extends State<RecipePreviewPage> {
               child: Column(
                 crossAxisAlignment: CrossAxisAlignment.start,
                 children: [
+                  if (widget.missingFields.isNotEmpty) ...[
+                    _buildReviewNotice(theme, l10n),
+                    const SizedBox(height: 16),
+                  ],
                   _buildTitle(theme),
                   if (widget.recipe.description != null) ...[
                     const SizedBox(height: 8),
@@ -145,6 +153,31 @@ class _RecipePreviewPageState extends State<RecipePreviewPage> {
     );
   }
 
+  Widget _buildReviewNotice(FThemeData theme, AppLocalizations l10n) {
+    return Container(
+      width: double.infinity,
+      padding: const EdgeInsets.all(12),
+      decoration: BoxDecoration(
+        color: theme.colors.secondary,
+        borderRadius: BorderRadius.circular(8),
+      ),
+      child: Row(
+        children: [
+          Icon(Icons.info_outline, color: theme.colors.secondaryForeground),
+          const SizedBox(width: 8),
+          Expanded(
+            child: Text(
+              l10n.reviewMissingFields(widget.missingFields.join(', ')),
+              style: theme.typography.sm.copyWith(
+                color: theme.colors.secondaryForeground,
+              ),
+            ),
+          ),
+        ],
+      ),
+    );
+  }
+
   Widget _buildDescription(FThemeData theme) {
     return Text(
       widget.recipe.description!,
@@ -17,6 +17,10 @@ void main() {
         RecipeRequestStatus.fromString('COMPLETED'),
         RecipeRequestStatus.completed,
       );
+      expect(
+        RecipeRequestStatus.fromString('NEEDS_REVIEW'),
+        RecipeRequestStatus.needsReview,
+      );
       expect(
         RecipeRequestStatus.fromString('FAILED'),
         RecipeRequestStatus.failed,
@@ -39,6 +43,7 @@ void main() {
       expect(RecipeRequestStatus.requested.value, 'REQUESTED');
       expect(RecipeRequestStatus.inProgress.value, 'IN_PROGRESS');
       expect(RecipeRequestStatus.completed.value, 'COMPLETED');
+      expect(RecipeRequestStatus.needsReview.value, 'NEEDS_REVIEW');
       expect(RecipeRequestStatus.failed.value, 'FAILED');
     });
   });
@@ -65,6 +70,27 @@ void main() {
       expect(result.userId, 'user-123');
       expect(result.createdAt, DateTime.utc(2025, 12, 17, 12));
       expect(result.updatedAt, DateTime.utc(2025, 12, 17, 12, 5));
+      expect(result.missingFields, isEmpty);
+    });
+
+    test('fromMap reads missing fields of a request needing review', () {
+      // Arrange
+      final inputMap = <String, dynamic>{
+        r'$id': 'test-id',
+        'url': 'https://example.com/recipe',
+        'status': 'NEEDS_REVIEW',
+        'user_id': 'user-123',
+        'missing_fields': <dynamic>['yield', 'times'],
+        r'$createdAt': '2025-12-17T12:00:00.000Z',
+        r'$updatedAt': '2025-12-17T12:05:00.000Z',
+      };
+
+      // Act
+      final result = RecipeRequest.fromMap(inputMap);
+
+      // Assert
+      expect(result.status, RecipeRequestStatus.needsReview);
+      expect(result.missingFields, ['yield', 'times']);
     });
 
     test('copyWith creates copy with replaced fields', () {
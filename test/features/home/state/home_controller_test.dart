@@ -328,6 +328,44 @@ void main() {
         expect(controller.recipes.value, contains(recipe));
       });
 
+      test('adds recipe with its missing fields when import needs review', () async {
+        // Arrange
+        const inputUrl = 'https://example.com/recipe';
+        const inputUserId = 'user-123';
+        final request = RecipeRequest(
+          id: 'test-id',
+          url: inputUrl,
+          status: RecipeRequestStatus.needsReview,
+          userId: inputUserId,
+          createdAt: DateTime.now(),
+          updatedAt: DateTime.now(),
+          missingFields: const ['yield', 'times'],
+        );
+        const recipe = Recipe(id: 'recipe-id', name: 'Test Recipe');
+
+        when(
+          () => mockImportService.importFromUrl(
+            url: inputUrl,
+            userId: inputUserId,
+          ),
+        ).thenAnswer((_) async => request);
+        when(
+          () => mockImportService.subscribeToRequest(request.id),
+        ).thenAnswer((_) => Stream.value(request));
+        when(
+          () => mockImportService.getExtractedRecipe(request.id),
+        ).thenAnswer((_) async => recipe);
+
+        // Act
+        await controller.importRecipe(url: inputUrl, userId: inputUserId);
+        await Future<void>.delayed(const Duration(milliseconds: 100));
+
+        // Assert
+        expect(controller.pendingImports.value, isEmpty);
+        expect(controller.recipes.value, contains(recipe));
+        expect(controller.missingFields.value[recipe.id], ['yield', 'times']);
+      });
+
       test('sets error on pending import when extraction fails', () async {
         // Arrange
         const inputUrl = 'https://example.com/recipe';